@@ -0,0 +1,296 @@
+package datatable
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	Sum   AggregateFunc = "sum"
+	Avg   AggregateFunc = "avg"
+	Min   AggregateFunc = "min"
+	Max   AggregateFunc = "max"
+	Count AggregateFunc = "count"
+	StDev AggregateFunc = "stdev"
+	Var   AggregateFunc = "var"
+)
+
+// AggregateFunc is the name of an aggregate function.
+type AggregateFunc string
+
+// resultType returns the result type of the function over a column of
+// the type t, Count is int64, StDev and Var are float64,the others keep t.
+func (f AggregateFunc) resultType(t ColumnType) (ColumnType, error) {
+	switch f {
+	case Count:
+		return Int64, nil
+	case Sum:
+		if isNumberType(t) {
+			return t, nil
+		}
+	case Avg:
+		if isNumberType(t) || t == Time {
+			return t, nil
+		}
+	case Min, Max:
+		if isNumberType(t) || t == Time || t == String {
+			return t, nil
+		}
+	case StDev, Var:
+		if isNumberType(t) {
+			return Float64, nil
+		}
+	default:
+		return "", fmt.Errorf("invalid aggregate function %q", string(f))
+	}
+	return "", fmt.Errorf("the aggregate function %s not support type %s", f, t)
+}
+
+// aggregator accumulates the values of one aggregate, the nil is skipped.
+type aggregator struct {
+	fn       AggregateFunc
+	dataType ColumnType
+	count    int64
+	sumInt   int64
+	sum      float64
+	//the running mean and sum of squares of differences(Welford)
+	mean, m2 float64
+	//min or max value,the first time of time average
+	value interface{}
+}
+
+func newAggregator(fn AggregateFunc, dataType ColumnType) (*aggregator, error) {
+	if _, err := fn.resultType(dataType); err != nil {
+		return nil, err
+	}
+	return &aggregator{fn: fn, dataType: dataType}, nil
+}
+func (a *aggregator) add(v interface{}) {
+	if v == nil {
+		return
+	}
+	a.count++
+	switch a.fn {
+	case Count:
+		return
+	case Min, Max:
+		if a.value == nil {
+			a.value = v
+			return
+		}
+		c := cmpValue(v, a.value)
+		if a.fn == Min && c < 0 || a.fn == Max && c > 0 {
+			a.value = v
+		}
+		return
+	}
+	var f float64
+	switch tv := v.(type) {
+	case int64:
+		a.sumInt += tv
+		f = float64(tv)
+	case float64:
+		f = tv
+	case time.Time:
+		if a.value == nil {
+			a.value = tv
+		}
+		f = float64(tv.Sub(a.value.(time.Time)))
+	}
+	a.sum += f
+	delta := f - a.mean
+	a.mean += delta / float64(a.count)
+	a.m2 += delta * (f - a.mean)
+}
+
+// result returns the aggregate value, nil when no value be added.
+func (a *aggregator) result() interface{} {
+	if a.fn == Count {
+		return a.count
+	}
+	if a.count == 0 {
+		return nil
+	}
+	switch a.fn {
+	case Min, Max:
+		return a.value
+	case Sum:
+		if a.dataType == Int64 {
+			return a.sumInt
+		}
+		return a.sum
+	case Avg:
+		switch a.dataType {
+		case Int64:
+			return a.sumInt / a.count
+		case Time:
+			return a.value.(time.Time).Add(time.Duration(a.mean))
+		}
+		return a.mean
+	}
+	//the sample variance
+	if a.count < 2 {
+		return nil
+	}
+	variance := a.m2 / float64(a.count-1)
+	if a.fn == StDev {
+		return math.Sqrt(variance)
+	}
+	return variance
+}
+
+// parseAggregate parses the "Func(column)" string, column is -1 for
+// "Count(*)".
+func (d *DataTable) parseAggregate(s string) (AggregateFunc, int, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return "", 0, fmt.Errorf("aggregate %q: %v", s, err)
+	}
+	if len(tokens) != 5 || tokens[0].kind != tokIdent || !tokens[1].isOp("(") ||
+		!tokens[3].isOp(")") || tokens[4].kind != tokEOF {
+		return "", 0, fmt.Errorf("invalid aggregate %q,must be Func(column)", s)
+	}
+	fn := AggregateFunc(strings.ToLower(tokens[0].text))
+	if tokens[2].isOp("*") && fn == Count {
+		return fn, -1, nil
+	}
+	if tokens[2].kind != tokIdent {
+		return "", 0, fmt.Errorf("invalid aggregate %q,must be Func(column)", s)
+	}
+	colIdx := d.ColumnIndex(tokens[2].text)
+	if colIdx == -1 {
+		return "", 0, ColumnNotFoundError(tokens[2].text)
+	}
+	return fn, colIdx, nil
+}
+
+// Compute computes the aggregate over the rows matching the filter, as the
+// .Net DataTable.Compute. The aggregate is one of Sum, Avg, Min, Max,
+// Count, StDev and Var with a column, such as "Sum(column2)" or "Count(*)".
+// The nulls are skipped, the result is nil if there is no value.
+func (d *DataTable) Compute(aggregate, filter string) (interface{}, error) {
+	fn, colIdx, err := d.parseAggregate(aggregate)
+	if err != nil {
+		return nil, err
+	}
+	dataType := Int64
+	if colIdx > -1 {
+		dataType = d.Columns[colIdx].DataType
+	}
+	agg, err := newAggregator(fn, dataType)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.selectRows(filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range rows {
+		if colIdx == -1 {
+			agg.add(true)
+		} else {
+			agg.add(d.GetValue(i, colIdx))
+		}
+	}
+	return agg.result(), nil
+}
+
+// Aggregation is an aggregate output column of GroupBy.
+type Aggregation struct {
+	Func AggregateFunc
+	//the source column, empty for Count of rows
+	Column string
+	//the output column name, default is func_column,such as sum_qty
+	Name string
+}
+
+func (a Aggregation) name() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	if a.Column == "" {
+		return string(a.Func)
+	}
+	return string(a.Func) + "_" + a.Column
+}
+
+// GroupBy groups the rows by the key columns and returns a new table, its
+// columns are the keys and the aggregate outputs, and its primary key is
+// the keys. The rows of the result are unchanged.
+func (d *DataTable) GroupBy(keys []string, aggs ...Aggregation) (*DataTable, error) {
+	result := NewDataTable(d.TableName)
+	keyIdx := make([]int, len(keys))
+	for i, k := range keys {
+		if keyIdx[i] = d.ColumnIndex(k); keyIdx[i] == -1 {
+			return nil, ColumnNotFoundError(k)
+		}
+		if result.ColumnIndex(k) > -1 {
+			return nil, ColumnExistsError
+		}
+		result.AddColumn(d.Columns[keyIdx[i]].Clone())
+	}
+	aggIdx := make([]int, len(aggs))
+	aggType := make([]ColumnType, len(aggs))
+	for i, a := range aggs {
+		aggIdx[i], aggType[i] = -1, Int64
+		if a.Column != "" {
+			if aggIdx[i] = d.ColumnIndex(a.Column); aggIdx[i] == -1 {
+				return nil, ColumnNotFoundError(a.Column)
+			}
+			aggType[i] = d.Columns[aggIdx[i]].DataType
+		} else if a.Func != Count {
+			return nil, fmt.Errorf("the aggregate %s need a column", a.Func)
+		}
+		t, err := a.Func.resultType(aggType[i])
+		if err != nil {
+			return nil, err
+		}
+		if result.ColumnIndex(a.name()) > -1 {
+			return nil, ColumnExistsError
+		}
+		result.AddColumn(NewDataColumn(a.name(), t, 0, a.Func == Count))
+	}
+
+	groups := map[string]int{}
+	var groupKeys [][]interface{}
+	var groupAggs [][]*aggregator
+	for row := 0; row < d.currentRows.Count(); row++ {
+		keyValues := make([]interface{}, len(keyIdx))
+		for i, colIdx := range keyIdx {
+			keyValues[i] = d.Columns[colIdx].Decode(d.currentRows.Get(colIdx, row))
+		}
+		hash := hashKey(keyValues)
+		g, ok := groups[hash]
+		if !ok {
+			g = len(groupKeys)
+			groups[hash] = g
+			groupKeys = append(groupKeys, keyValues)
+			list := make([]*aggregator, len(aggs))
+			for i, a := range aggs {
+				list[i] = &aggregator{fn: a.Func, dataType: aggType[i]}
+			}
+			groupAggs = append(groupAggs, list)
+		}
+		for i, agg := range groupAggs[g] {
+			if aggIdx[i] == -1 {
+				agg.add(true)
+			} else {
+				agg.add(d.Columns[aggIdx[i]].Decode(d.currentRows.Get(aggIdx[i], row)))
+			}
+		}
+	}
+	result.SetPK(keys...)
+	for g, keyValues := range groupKeys {
+		values := keyValues
+		for _, agg := range groupAggs[g] {
+			values = append(values, agg.result())
+		}
+		if err := result.addValues(values); err != nil {
+			return nil, err
+		}
+	}
+	result.AcceptChange()
+	return result, nil
+}
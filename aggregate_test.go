@@ -0,0 +1,120 @@
+package datatable
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompute(t *testing.T) {
+	table := createSelectData()
+	t1 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		aggregate string
+		filter    string
+		want      interface{}
+	}{
+		{"Sum(column2)", "", int64(24)},
+		{"sum(column2)", "column1 LIKE 'row%'", int64(15)},
+		{"Avg(column2)", "", int64(6)},
+		{"Avg(x)", "", 2.0},
+		{"Sum(x)", "", 4.0},
+		{"Min(x)", "", 1.5},
+		{"Max(column1)", "", "row3"},
+		{"Count(x)", "", int64(2)},
+		{"Count(*)", "", int64(4)},
+		{"Count(*)", "column2 > 100", int64(0)},
+		{"Sum(x)", "column2 > 100", nil},
+		{"Min(t)", "", t1},
+		{"Max(t)", "", t1.AddDate(0, 2, 0)},
+		{"Avg(t)", "column2 < 7", t1.Add(t1.AddDate(0, 1, 0).Sub(t1) / 2)},
+		{"Var(x)", "", 0.5},
+		{"StDev(x)", "", math.Sqrt(0.5)},
+		{"StDev(x)", "x = 1.5", nil},
+	}
+	for _, c := range cases {
+		v, err := table.Compute(c.aggregate, c.filter)
+		if err != nil {
+			t.Error(c.aggregate, err)
+			continue
+		}
+		if tv, ok := v.(time.Time); ok {
+			if !tv.Equal(c.want.(time.Time)) {
+				t.Error(c.aggregate, v)
+			}
+		} else if v != c.want {
+			t.Errorf("%s %s:%v(%T)", c.aggregate, c.filter, v, v)
+		}
+	}
+	for _, aggregate := range []string{"Sum(column1)", "Sum(t)", "Var(t)", "Median(x)", "Sum(column9)", "Sum(*)", "column2"} {
+		if _, err := table.Compute(aggregate, ""); err == nil {
+			t.Error("must be error:", aggregate)
+		}
+	}
+}
+func TestGroupBy(t *testing.T) {
+	table := NewDataTable("sales")
+	table.AddColumn(NewInt64Column("id"))
+	table.AddColumn(NewStringColumn("customer"))
+	table.AddColumn(StringColumn("region", 0, false))
+	table.AddColumn(Float64Column("amount", false))
+	table.SetPK("id")
+	table.AddValues(int64(1), "c1", "north", 10.0)
+	table.AddValues(int64(2), "c2", "south", 20.0)
+	table.AddValues(int64(3), "c1", "north", nil)
+	table.AddValues(int64(4), "c1", nil, 5.0)
+	table.AddValues(int64(5), "c2", "south", 30.0)
+
+	result, err := table.GroupBy([]string{"customer", "region"},
+		Aggregation{Func: Sum, Column: "amount"},
+		Aggregation{Func: Count, Name: "rows"},
+		Aggregation{Func: Max, Column: "amount", Name: "top"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RowCount() != 3 || result.HasChange() {
+		t.Error("error", result.RowCount())
+	}
+	if !reflect.DeepEqual(result.ColumnNames(), []string{"customer", "region", "sum_amount", "rows", "top"}) {
+		t.Error(result.ColumnNames())
+	}
+	if !reflect.DeepEqual(result.PK, []string{"customer", "region"}) {
+		t.Error(result.PK)
+	}
+	if r := result.Row(result.Find("c1", "north")); r["sum_amount"] != 10.0 || r["rows"] != int64(2) || r["top"] != 10.0 {
+		t.Error(r)
+	}
+	if r := result.Row(result.Find("c2", "south")); r["sum_amount"] != 50.0 || r["rows"] != int64(2) {
+		t.Error(r)
+	}
+	if i := result.Find("c1", nil); i == -1 || result.Row(i)["rows"] != int64(1) {
+		t.Error("error", i)
+	}
+	if _, err := table.GroupBy([]string{"customer"}, Aggregation{Func: Sum, Column: "region"}); err == nil {
+		t.Error("must be error")
+	}
+	if _, err := table.GroupBy([]string{"nothing"}); err == nil {
+		t.Error("must be error")
+	}
+}
+
+func TestGroupByNullKey(t *testing.T) {
+	table := NewDataTable("sales")
+	table.AddColumn(NewInt64Column("id"))
+	table.AddColumn(StringColumn("region", 0, false))
+	table.AddColumn(NewFloat64Column("amount"))
+	table.SetPK("id")
+	table.AddValues(int64(1), "north", 10.0)
+	table.AddValues(int64(2), nil, 20.0)
+	table.AddValues(int64(3), nil, 5.0)
+	//the null key is kept, not filled by the default of the key column
+	table.Columns[1].DefaultValue = "south"
+	result, err := table.GroupBy([]string{"region"}, Aggregation{Func: Sum, Column: "amount"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := result.Find(nil); result.RowCount() != 2 || i == -1 || result.GetValue(i, 1) != 25.0 {
+		t.Error(result.AsCsv())
+	}
+}
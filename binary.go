@@ -0,0 +1,487 @@
+package datatable
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"reflect"
+	"time"
+)
+
+// the binary snapshot layout:
+//
+//	magic "DTBL" | version uint16 | body length uint64 | body | crc32(body)
+//
+// the body holds the schema, the rows in storage order with one typed
+// block per column, the row status, the origin data of the updated rows,
+// the deleted rows, the primary key order and the secondary indexes. The
+// writer counts the body length by a first pass and streams the body by
+// the second, so the body is never held in memory.
+const (
+	snapshotMagic   = "DTBL"
+	snapshotVersion = 1
+)
+
+// InvalidSnapshotError is returned when ReadFrom reads corrupt data.
+var InvalidSnapshotError = errors.New("invalid snapshot data")
+
+// SnapshotVersionError returns the error of an unsupported snapshot version.
+func SnapshotVersionError(version int) error {
+	return fmt.Errorf("snapshot version %d not supported, want %d", version, snapshotVersion)
+}
+
+// WriteTo writes the table as a binary snapshot to w, implements the
+// io.WriterTo. The snapshot holds the schema, the rows, the changes and
+// the indexes, ReadFrom restores it.
+func (d *DataTable) WriteTo(w io.Writer) (int64, error) {
+	size := &binWriter{w: io.Discard}
+	if err := size.table(d); err != nil {
+		return 0, err
+	}
+	out := &binWriter{w: w}
+	buf := bufio.NewWriter(out)
+	buf.WriteString(snapshotMagic)
+	binary.Write(buf, binary.LittleEndian, uint16(snapshotVersion))
+	binary.Write(buf, binary.LittleEndian, uint64(size.n))
+	crc := crc32.NewIEEE()
+	body := &binWriter{w: io.MultiWriter(buf, crc)}
+	if err := body.table(d); err != nil {
+		return out.n, err
+	}
+	if body.err != nil {
+		return out.n, body.err
+	}
+	binary.Write(buf, binary.LittleEndian, crc.Sum32())
+	err := buf.Flush()
+	return out.n, err
+}
+
+// ReadFrom replaces the table with the snapshot written by WriteTo,
+// implements the io.ReaderFrom. The table is unchanged if an error is
+// returned.
+func (d *DataTable) ReadFrom(r io.Reader) (int64, error) {
+	header := make([]byte, len(snapshotMagic)+2+8)
+	n, err := io.ReadFull(r, header)
+	if err != nil {
+		return int64(n), InvalidSnapshotError
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return int64(n), InvalidSnapshotError
+	}
+	if v := binary.LittleEndian.Uint16(header[len(snapshotMagic):]); v != snapshotVersion {
+		return int64(n), SnapshotVersionError(int(v))
+	}
+	size := binary.LittleEndian.Uint64(header[len(snapshotMagic)+2:])
+	if size > math.MaxInt64-4 {
+		return int64(n), InvalidSnapshotError
+	}
+	//copy by steps, so a corrupt length doesn't allocate a huge buffer
+	buf := &bytes.Buffer{}
+	m, err := io.CopyN(buf, r, int64(size)+4)
+	if err != nil {
+		return int64(n) + m, InvalidSnapshotError
+	}
+	data := buf.Bytes()
+	body := data[:size]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[size:]) {
+		return int64(n) + m, InvalidSnapshotError
+	}
+	br := &binReader{data: body}
+	result := br.table()
+	if br.err == nil && len(br.data) > 0 {
+		br.err = InvalidSnapshotError
+	}
+	if br.err != nil {
+		return int64(n) + m, br.err
+	}
+	return int64(n) + m, d.replaceWith(result)
+}
+
+// binWriter writes to w and counts the bytes, the first error is kept in
+// err and the following writes do nothing.
+type binWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (w *binWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	w.err = err
+	return n, err
+}
+func (w *binWriter) WriteByte(c byte) error {
+	_, err := w.Write([]byte{c})
+	return err
+}
+func (w *binWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *binWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.Write(b[:binary.PutUvarint(b[:], v)])
+}
+func (w *binWriter) varint(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	w.Write(b[:binary.PutVarint(b[:], v)])
+}
+func (w *binWriter) bytes(v []byte) {
+	w.uvarint(uint64(len(v)))
+	w.Write(v)
+}
+func (w *binWriter) string(v string) {
+	w.uvarint(uint64(len(v)))
+	w.WriteString(v)
+}
+func (w *binWriter) bool(v bool) {
+	if v {
+		w.WriteByte(1)
+	} else {
+		w.WriteByte(0)
+	}
+}
+func (w *binWriter) table(d *DataTable) error {
+	w.string(d.TableName)
+	w.uvarint(uint64(d.ColumnCount()))
+	for _, c := range d.Columns {
+		w.string(c.Name)
+		w.string(string(c.DataType))
+		w.varint(int64(c.MaxSize))
+		w.bool(c.NotNull)
+		w.bool(c.DefaultValue != nil)
+		if c.DefaultValue != nil {
+			if err := c.Valid(c.DefaultValue); err != nil {
+				return fmt.Errorf("the default value: %v", err)
+			}
+			if err := w.value(c.DataType, c.Decode(c.Encode(c.DefaultValue))); err != nil {
+				return fmt.Errorf("column %q: %v", c.Name, err)
+			}
+		}
+		w.bool(c.AutoIncrement)
+		w.varint(c.AutoIncrementSeed)
+		w.varint(c.AutoIncrementStep)
+		w.bool(c.autoIncrementUsed)
+		w.varint(c.autoIncrementNext)
+		w.string(c.Expression)
+	}
+	w.uvarint(uint64(len(d.PK)))
+	for _, c := range d.PK {
+		w.string(c)
+	}
+	if err := w.rows(d, d.currentRows); err != nil {
+		return err
+	}
+	w.Write(d.rowStatus)
+	origin := &dataRows{}
+	for _, c := range d.Columns {
+		origin.AddColumn(c.StoreType())
+	}
+	for i, status := range d.rowStatus {
+		if status == UPDATE {
+			origin.AddRow(d.originData[i])
+		}
+	}
+	if err := w.rows(d, origin); err != nil {
+		return err
+	}
+	if err := w.rows(d, d.deleteRows); err != nil {
+		return err
+	}
+	if d.HasPrimaryKey() {
+		for _, v := range d.primaryIndexes.index {
+			w.uvarint(uint64(v))
+		}
+	}
+	w.uvarint(uint64(len(d.indexes)))
+	for _, x := range d.indexes {
+		w.string(x.name)
+		w.bool(x.unique)
+		w.uvarint(uint64(len(x.columns)))
+		for _, c := range x.columns {
+			w.uvarint(uint64(c))
+		}
+	}
+	return nil
+}
+
+// rows writes the row count and one block per column, the block of a
+// nullable column starts with the null bitmap and holds the not null
+// values only.
+func (w *binWriter) rows(d *DataTable, rows *dataRows) error {
+	count := rows.Count()
+	w.uvarint(uint64(count))
+	for i, c := range d.Columns {
+		values := make([]interface{}, count)
+		for j := range values {
+			values[j] = c.Decode(rows.Get(i, j))
+		}
+		if !c.NotNull {
+			bitmap := make([]byte, (count+7)/8)
+			for j, v := range values {
+				if v == nil {
+					bitmap[j/8] |= 1 << uint(j%8)
+				}
+			}
+			w.Write(bitmap)
+		}
+		for _, v := range values {
+			if v == nil {
+				continue
+			}
+			if err := w.value(c.DataType, v); err != nil {
+				return fmt.Errorf("column %q: %v", c.Name, err)
+			}
+		}
+	}
+	return nil
+}
+func (w *binWriter) value(t ColumnType, v interface{}) error {
+	switch t {
+	case String:
+		w.string(v.(string))
+	case Int64:
+		w.varint(v.(int64))
+	case Float64:
+		binary.Write(w, binary.LittleEndian, math.Float64bits(v.(float64)))
+	case Bool:
+		w.bool(v.(bool))
+	case Time:
+		bys, err := v.(time.Time).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		w.bytes(bys)
+	case Bytea:
+		w.bytes(v.([]byte))
+	default:
+		return fmt.Errorf("column type %q invalid", t)
+	}
+	return nil
+}
+
+// binReader reads the snapshot body, the first error is kept in err and
+// the following reads return zero values.
+type binReader struct {
+	data []byte
+	err  error
+}
+
+func (r *binReader) fail() {
+	if r.err == nil {
+		r.err = InvalidSnapshotError
+	}
+	r.data = nil
+}
+func (r *binReader) next(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.data) {
+		r.fail()
+		return nil
+	}
+	result := r.data[:n]
+	r.data = r.data[n:]
+	return result
+}
+func (r *binReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+func (r *binReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+// count reads a count of items, every item takes at least 1/perByte byte.
+func (r *binReader) count(perByte int) int {
+	v := r.uvarint()
+	if v > uint64(len(r.data))*uint64(perByte) {
+		r.fail()
+		return 0
+	}
+	return int(v)
+}
+func (r *binReader) bytes() []byte {
+	n := r.count(1)
+	return append([]byte{}, r.next(n)...)
+}
+func (r *binReader) string() string {
+	return string(r.next(r.count(1)))
+}
+func (r *binReader) bool() bool {
+	b := r.next(1)
+	if len(b) == 0 || b[0] > 1 {
+		r.fail()
+		return false
+	}
+	return b[0] == 1
+}
+func (r *binReader) table() *DataTable {
+	d := NewDataTable(r.string())
+	columnCount := r.count(1)
+	for i := 0; i < columnCount && r.err == nil; i++ {
+		c := NewDataColumn(r.string(), ColumnType(r.string()), int(r.varint()), r.bool())
+		if _, ok := reflectType[c.DataType]; !ok || d.ColumnIndex(c.Name) > -1 {
+			r.fail()
+			break
+		}
+		if r.bool() {
+			c.DefaultValue = r.value(c.DataType)
+		}
+		c.AutoIncrement = r.bool()
+		c.AutoIncrementSeed = r.varint()
+		c.AutoIncrementStep = r.varint()
+		c.autoIncrementUsed = r.bool()
+		c.autoIncrementNext = r.varint()
+		if c.Expression = r.string(); c.IsComputed() && c.compileExpression(d) != nil {
+			r.fail()
+			break
+		}
+		d.AddColumn(c)
+	}
+	pk := make([]string, r.count(1))
+	for i := range pk {
+		if pk[i] = r.string(); d.ColumnIndex(pk[i]) == -1 {
+			r.fail()
+			break
+		}
+	}
+	d.currentRows = r.rows(d)
+	count := d.currentRows.Count()
+	d.rowStatus = append([]byte{}, r.next(count)...)
+	d.originData = make([][]interface{}, count)
+	updated := []int{}
+	for i, status := range d.rowStatus {
+		switch status {
+		case UNCHANGE, INSERT:
+		case UPDATE:
+			updated = append(updated, i)
+		default:
+			r.fail()
+		}
+		if status != UNCHANGE {
+			d.changedRows++
+		}
+		d.changed = d.changed || status != UNCHANGE
+	}
+	origin := r.rows(d)
+	if r.err == nil && origin.Count() != len(updated) {
+		r.fail()
+	}
+	if r.err != nil {
+		return nil
+	}
+	for i, trueIndex := range updated {
+		d.originData[trueIndex] = origin.GetRow(i)
+	}
+	d.deleteRows = r.rows(d)
+	if r.err != nil {
+		return nil
+	}
+	d.changed = d.changed || d.deleteRows.Count() > 0
+	d.PK = pk
+	if len(pk) > 0 {
+		used := make([]bool, count)
+		d.primaryIndexes.index = make([]int, count)
+		for i := range d.primaryIndexes.index {
+			v := r.uvarint()
+			if v >= uint64(count) || used[v] {
+				r.fail()
+				return nil
+			}
+			used[v] = true
+			d.primaryIndexes.index[i] = int(v)
+		}
+	}
+	indexCount := r.count(1)
+	for i := 0; i < indexCount && r.err == nil; i++ {
+		x := &dataIndex{dataTable: d, name: r.string(), unique: r.bool()}
+		x.columns = make([]int, r.count(1))
+		for j := range x.columns {
+			v := r.uvarint()
+			if v >= uint64(d.ColumnCount()) {
+				r.fail()
+				return nil
+			}
+			x.columns[j] = int(v)
+		}
+		if d.indexByName(x.name) != nil || len(x.columns) == 0 {
+			r.fail()
+			return nil
+		}
+		x.rebuild()
+		d.indexes = append(d.indexes, x)
+	}
+	return d
+}
+func (r *binReader) rows(d *DataTable) *dataRows {
+	rows := &dataRows{}
+	count := r.count(8)
+	for _, c := range d.Columns {
+		if r.err != nil {
+			return rows
+		}
+		slice := reflect.MakeSlice(reflect.SliceOf(c.StoreType()), count, count)
+		var bitmap []byte
+		if !c.NotNull {
+			bitmap = r.next((count + 7) / 8)
+		}
+		for j := 0; j < count && r.err == nil; j++ {
+			var v interface{}
+			if bitmap == nil || bitmap[j/8]&(1<<uint(j%8)) == 0 {
+				v = r.value(c.DataType)
+			}
+			slice.Index(j).Set(ValueOf(c.Encode(v)))
+		}
+		rows.data = append(rows.data, slice.Interface())
+	}
+	return rows
+}
+func (r *binReader) value(t ColumnType) interface{} {
+	switch t {
+	case String:
+		return r.string()
+	case Int64:
+		return r.varint()
+	case Float64:
+		b := r.next(8)
+		if b == nil {
+			return float64(0)
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	case Bool:
+		return r.bool()
+	case Time:
+		var v time.Time
+		if err := v.UnmarshalBinary(r.bytes()); err != nil {
+			r.fail()
+		}
+		return v
+	default:
+		return r.bytes()
+	}
+}
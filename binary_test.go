@@ -0,0 +1,68 @@
+package datatable
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteToReadFrom(t *testing.T) {
+	src := CreateTestData()
+	src.AddColumn(TimeColumn("t", false))
+	src.AddColumn(ByteaColumn("b", false))
+	src.AddColumn(Float64Column("f", true))
+	src.AddColumn(BoolColumn("ok", false))
+	t1 := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	src.SetValues(0, append(src.GetValues(0)[:3], t1, []byte{1, 2}, 1.5, true)...)
+	if err := src.CreateIndex("idx", []string{"column3"}, false); err != nil {
+		t.Fatal(err)
+	}
+	src.AcceptChange()
+	src.SetValues(1, append(src.GetValues(1)[:2], "changed", t1, nil, 2.5, nil)...)
+	src.DeleteRow(2)
+	src.AddValues("new", int64(1), "test", nil, []byte{}, 0.0, false)
+
+	buf := &bytes.Buffer{}
+	n, err := src.WriteTo(buf)
+	if err != nil || n != int64(buf.Len()) {
+		t.Fatal(n, err)
+	}
+	data := buf.Bytes()
+	table := NewDataTable("")
+	if m, err := table.ReadFrom(bytes.NewReader(data)); err != nil || m != n {
+		t.Fatal(m, err)
+	}
+	if table.TableName != src.TableName || !reflect.DeepEqual(table.PK, src.PK) ||
+		!reflect.DeepEqual(table.Rows(), src.Rows()) || !table.HasChange() {
+		t.Error(table.AsCsv())
+	}
+	if !reflect.DeepEqual(table.GetChange(), src.GetChange()) {
+		t.Error(table.GetChange())
+	}
+	if i := table.Find("new", int64(1)); i == -1 || table.GetValue(i, 3) != nil {
+		t.Error("error", i)
+	}
+	if rows := table.SearchBy("idx", "test"); len(rows) != 2 {
+		t.Error(rows)
+	}
+	//errors
+	corrupt := append([]byte{}, data...)
+	corrupt[len(corrupt)-10]++
+	versioned := append([]byte{}, data...)
+	versioned[4] = 9
+	for i, bys := range [][]byte{nil, []byte("DTBX"), data[:len(data)-1], corrupt, versioned} {
+		if _, err := table.ReadFrom(bytes.NewReader(bys)); err == nil {
+			t.Error("must be error", i)
+		}
+	}
+	if _, err := table.ReadFrom(bytes.NewReader(versioned)); err == nil || err.Error() != "snapshot version 9 not supported, want 1" {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(table.Rows(), src.Rows()) {
+		t.Error("the table changed by error")
+	}
+	if _, err := src.WriteTo(failWriter{}); err == nil || err.Error() != "disk full" {
+		t.Error(err)
+	}
+}
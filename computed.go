@@ -0,0 +1,133 @@
+package datatable
+
+import "fmt"
+
+// IsComputed reports whether the column is computed by the Expression.
+func (d *DataColumn) IsComputed() bool {
+	return d.Expression != ""
+}
+
+// compileExpression compiles the Expression of the column against the
+// columns of the table, the result type must be the column type or a
+// number type to the number column.
+func (d *DataColumn) compileExpression(table *DataTable) error {
+	e, err := compileExpression(table, d.Expression)
+	if err != nil {
+		return err
+	}
+	if t := e.dataType(); t != d.DataType && t != "" && !(isNumberType(t) && isNumberType(d.DataType)) {
+		return fmt.Errorf("the column [%s] expression %q: the result type %s not is %s", d.Name, d.Expression, t, d.DataType)
+	}
+	d.expr = e
+	return nil
+}
+
+// computeValue returns the value of the expression for the decoded
+// values of the row.
+func (d *DataColumn) computeValue(values []interface{}) (interface{}, error) {
+	v, err := d.expr.eval(values)
+	if err != nil {
+		return nil, fmt.Errorf("the column [%s]: %v", d.Name, err)
+	}
+	if f, ok := v.(float64); ok && d.DataType == Int64 {
+		return int64(f), nil
+	}
+	return convertNumber(v, d.DataType), nil
+}
+
+func (d *DataTable) hasComputed() bool {
+	for _, c := range d.Columns {
+		if c.IsComputed() {
+			return true
+		}
+	}
+	return false
+}
+
+// computeValues returns the decoded values with the computed columns
+// recalculated, the provided values of them are ignored. The computed
+// column can use the computed columns before it. The invalid values are
+// returned as they are, addValues or setValues reports them.
+func (d *DataTable) computeValues(vs []interface{}) ([]interface{}, error) {
+	if len(vs) != d.ColumnCount() || !d.hasComputed() {
+		return vs, nil
+	}
+	values := append([]interface{}{}, vs...)
+	for i, c := range d.Columns {
+		if c.IsComputed() {
+			values[i] = c.Decode(c.ZeroValue())
+		}
+	}
+	data, err := d.validValues(values)
+	if err != nil {
+		return vs, nil
+	}
+	values = d.decodeValues(data)
+	for i, c := range d.Columns {
+		if !c.IsComputed() {
+			continue
+		}
+		if values[i], err = c.computeValue(values); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// fillComputed stores the values of the new computed column in the rows,
+// the origin rows and the deleted rows. The value can't be computed is
+// left zero.
+func (d *DataTable) fillComputed(c *DataColumn) {
+	fill := func(values []interface{}) {
+		if v, err := c.computeValue(d.decodeValues(values)); err == nil && c.Valid(v) == nil {
+			values[c.index] = c.Encode(v)
+		}
+	}
+	for i := 0; i < d.currentRows.Count(); i++ {
+		values := d.currentRows.GetRow(i)
+		fill(values)
+		d.currentRows.Set(c.index, i, values[c.index])
+		if d.rowStatus[i] == UPDATE {
+			fill(d.originData[i])
+		}
+	}
+	for i := 0; i < d.deleteRows.Count(); i++ {
+		values := d.deleteRows.GetRow(i)
+		fill(values)
+		d.deleteRows.Set(c.index, i, values[c.index])
+	}
+}
+
+// clearComputed sets the values of the computed columns of the change
+// rows to the zero store value, the typed null of the nullable column, so
+// the rows still decode.
+func (d *DataTable) clearComputed(rows []*ChangeRow) {
+	for _, r := range rows {
+		if r.OriginData != nil {
+			//the origin data is stored by the table
+			r.OriginData = append([]interface{}{}, r.OriginData...)
+		}
+		for i, c := range d.Columns {
+			if !c.IsComputed() {
+				continue
+			}
+			if r.Data != nil {
+				r.Data[i] = c.ZeroValue()
+			}
+			if r.OriginData != nil {
+				r.OriginData[i] = c.ZeroValue()
+			}
+		}
+	}
+}
+
+// GetChangeWithComputed is GetChange including the values of the
+// computed columns.
+func (d *DataTable) GetChangeWithComputed() *TableChange {
+	result := &TableChange{}
+	result.DeleteRows = d.getChangeDelete()
+	result.UpdateRows = d.getChangeUpdate()
+	result.InsertRows = d.getChangeInsert()
+	result.RowCount = len(result.DeleteRows) + len(result.UpdateRows) + len(result.InsertRows)
+	return result
+}
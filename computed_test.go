@@ -0,0 +1,124 @@
+package datatable
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestComputedColumns(t *testing.T) {
+	table := NewDataTable("lines")
+	table.AddColumn(NewInt64Column("id"))
+	table.AddColumn(NewInt64Column("qty"))
+	table.AddColumn(NewFloat64Column("price"))
+	table.AddColumn(Float64Column("discount", false))
+	table.SetPK("id")
+	table.AddValues(int64(1), int64(2), 1.5, nil)
+	table.AcceptChange()
+	total := NewFloat64Column("total")
+	total.Expression = "qty * price"
+	table.AddColumn(total)
+	net := Float64Column("net", false)
+	net.Expression = "IIF(discount IS NULL, total, total - discount)"
+	table.AddColumn(net)
+	if table.GetValue(0, 4) != 3.0 || table.GetValue(0, 5) != 3.0 {
+		t.Error(table.AsCsv())
+	}
+	//the provided values are ignored
+	if err := table.AddValues(int64(2), int64(3), 2.0, 1.0, 100.0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if table.GetValue(1, 4) != 6.0 || table.GetValue(1, 5) != 5.0 {
+		t.Error(table.AsCsv())
+	}
+	if err := table.SetValues(0, int64(1), int64(4), 1.5, 0.5, 0.0, 0.0); err != nil {
+		t.Fatal(err)
+	}
+	if table.GetValue(0, 4) != 6.0 || table.GetValue(0, 5) != 5.5 {
+		t.Error(table.AsCsv())
+	}
+	if !strings.Contains(table.AsCsv(), "total") || !strings.Contains(table.AsTabText(), "5.5") {
+		t.Error(table.AsTabText())
+	}
+	chg := table.GetChange()
+	if len(chg.UpdateRows) != 1 || chg.UpdateRows[0].Data[4] != 0.0 || len(chg.InsertRows) != 1 || chg.InsertRows[0].Data[4] != 0.0 {
+		t.Error(chg)
+	}
+	//the nullable computed column decodes as null
+	if table.Columns[5].Decode(chg.UpdateRows[0].OriginData[5]) != nil || table.Columns[5].Decode(chg.InsertRows[0].Data[5]) != nil ||
+		table.decodeValues(chg.UpdateRows[0].Data)[5] != nil {
+		t.Error(chg)
+	}
+	chg = table.GetChangeWithComputed()
+	if table.Columns[4].Decode(chg.UpdateRows[0].Data[4]) != 6.0 || table.Columns[5].Decode(chg.UpdateRows[0].OriginData[5]) != 3.0 {
+		t.Error(chg)
+	}
+	stmts, err := table.changeStatements(PostgreSQL, SaveOptions{CheckAllColumns: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range stmts {
+		if strings.Contains(s.query, "total") || strings.Contains(s.query, "net") {
+			t.Error(s.query)
+		}
+	}
+	//the copy of the column is not computed
+	if c := total.Clone(); c.IsComputed() {
+		t.Error(c)
+	}
+	bad := NewStringColumn("bad")
+	bad.Expression = "qty + 1"
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("must be panic")
+			}
+		}()
+		table.AddColumn(bad)
+	}()
+	if table.ColumnCount() != 6 {
+		t.Error(table.ColumnNames())
+	}
+}
+
+func TestComputedColumnsPersisted(t *testing.T) {
+	table := NewDataTable("lines")
+	table.AddColumn(NewInt64Column("id"))
+	table.AddColumn(NewInt64Column("qty"))
+	table.AddColumn(NewFloat64Column("price"))
+	total := NewFloat64Column("total")
+	total.Expression = "qty * price"
+	table.AddColumn(total)
+	table.SetPK("id")
+	table.AddValues(int64(1), int64(2), 1.5, nil)
+	bys, err := json.Marshal(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := NewDataTable("")
+	if err := json.Unmarshal(bys, read); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if _, err := table.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := NewDataTable("")
+	if _, err := snapshot.ReadFrom(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []*DataTable{read, snapshot} {
+		if !d.Columns[3].IsComputed() || d.GetValue(0, 3) != 3.0 {
+			t.Error(d.AsCsv())
+		}
+		if err := d.SetValues(0, int64(1), int64(4), 1.5, 0.0); err != nil || d.GetValue(0, 3) != 6.0 {
+			t.Error(err, d.AsCsv())
+		}
+	}
+	//the expression is compiled against the columns read
+	bad := strings.Replace(string(bys), "qty * price", "qty * nothing", 1)
+	if err := json.Unmarshal([]byte(bad), NewDataTable("")); err == nil {
+		t.Error("must be error")
+	}
+}
@@ -0,0 +1,330 @@
+package datatable
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConstraintExistsError is returned when add a constraint with the name
+// exists in the table.
+var ConstraintExistsError = errors.New("the constraint exists")
+
+// ConstraintNotFoundError returns the error of the constraint not found.
+func ConstraintNotFoundError(name string) error {
+	return fmt.Errorf("the constraint [%s] not found", name)
+}
+
+// ConstraintViolationsError is returned by EnableConstraints, it reports
+// every violating row.
+type ConstraintViolationsError struct {
+	Violations []*ConstraintError
+}
+
+func (e *ConstraintViolationsError) Error() string {
+	return fmt.Sprintf("%d rows violate the constraints, the first: %v", len(e.Violations), e.Violations[0])
+}
+
+// tableConstraint is a constraint checked by AddValues and SetValues.
+type tableConstraint interface {
+	Name() string
+	//checkRow checks the decoded values of the row at the true index, -1
+	//for the row being added
+	checkRow(values []interface{}, trueIndex int) *ConstraintError
+	usesColumn(col int) bool
+}
+
+// UniqueConstraint rejects the rows having the same values of the
+// columns, a key with null is not duplicate. It is backed by an index.
+type UniqueConstraint struct {
+	Columns []string
+	table   *DataTable
+	name    string
+}
+
+// Name returns the name of the constraint, UQ_ and the column names.
+func (c *UniqueConstraint) Name() string {
+	return c.name
+}
+func (c *UniqueConstraint) indexName() string {
+	return "constraint:" + c.name
+}
+
+// index returns the index of the constraint, created when missing.
+func (c *UniqueConstraint) index() (*dataIndex, error) {
+	if x := c.table.indexByName(c.indexName()); x != nil {
+		return x, nil
+	}
+	if err := c.table.CreateIndex(c.indexName(), c.Columns, false); err != nil {
+		return nil, err
+	}
+	return c.table.indexByName(c.indexName()), nil
+}
+func (c *UniqueConstraint) checkRow(values []interface{}, trueIndex int) *ConstraintError {
+	x, err := c.index()
+	if err != nil {
+		return &ConstraintError{Constraint: c.name, Table: c.table.TableName, Key: c.table.getPkValues(values), Message: err.Error()}
+	}
+	key := make([]interface{}, len(x.columns))
+	for i, col := range x.columns {
+		key[i] = values[col]
+	}
+	if x.contains(key, trueIndex) {
+		return &ConstraintError{Constraint: c.name, Table: c.table.TableName, Key: key, Message: "the key is duplicate"}
+	}
+	return nil
+}
+func (c *UniqueConstraint) usesColumn(col int) bool {
+	for _, name := range c.Columns {
+		if c.table.ColumnIndex(name) == col {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckConstraint rejects the rows the boolean expression is false for,
+// like SQL a NULL result passes.
+type CheckConstraint struct {
+	Expression string
+	table      *DataTable
+	name       string
+	expr       *expression
+}
+
+// Name returns the name of the constraint.
+func (c *CheckConstraint) Name() string {
+	return c.name
+}
+func (c *CheckConstraint) checkRow(values []interface{}, trueIndex int) *ConstraintError {
+	v, err := c.expr.eval(values)
+	if err == nil && v != false {
+		return nil
+	}
+	msg := "the check is false"
+	if err != nil {
+		msg = err.Error()
+	}
+	return &ConstraintError{Constraint: c.name, Table: c.table.TableName, Key: c.table.getPkValues(values), Message: msg}
+}
+func (c *CheckConstraint) usesColumn(col int) bool {
+	for _, v := range c.expr.columns {
+		if v == col {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *DataTable) constraintByName(name string) tableConstraint {
+	for _, c := range d.constraints {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// addConstraint adds the constraint, the existing rows must satisfy it
+// unless the constraints are disabled.
+func (d *DataTable) addConstraint(c tableConstraint) error {
+	if d.constraintByName(c.Name()) != nil {
+		return ConstraintExistsError
+	}
+	if !d.constraintsDisabled {
+		if errs := d.violations(c); len(errs) > 0 {
+			return errs[0]
+		}
+	}
+	d.constraints = append(d.constraints, c)
+	return nil
+}
+
+// AddUniqueConstraint adds a unique constraint on the columns other than
+// the primary key, named UQ_ and the column names joined by _.
+func (d *DataTable) AddUniqueConstraint(columns ...string) (*UniqueConstraint, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("the unique constraint has no column")
+	}
+	for _, col := range columns {
+		if d.ColumnIndex(col) == -1 {
+			return nil, ColumnNotFoundError(col)
+		}
+	}
+	if len(d.PK) > 0 && strings.Join(columns, ",") == strings.Join(d.PK, ",") {
+		return nil, errors.New("the columns of the unique constraint are the primary key")
+	}
+	c := &UniqueConstraint{Columns: columns, table: d, name: "UQ_" + strings.Join(columns, "_")}
+	if d.constraintByName(c.name) != nil {
+		return nil, ConstraintExistsError
+	}
+	if _, err := c.index(); err != nil {
+		return nil, err
+	}
+	if err := d.addConstraint(c); err != nil {
+		d.DropIndex(c.indexName())
+		return nil, err
+	}
+	return c, nil
+}
+
+// AddCheckConstraint adds a check constraint of the boolean expression
+// as DataTable.Select, e.g. "qty >= 0 AND price > 0".
+func (d *DataTable) AddCheckConstraint(name, expr string) (*CheckConstraint, error) {
+	e, err := compileFilter(d, expr)
+	if err != nil {
+		return nil, err
+	}
+	c := &CheckConstraint{Expression: expr, table: d, name: name, expr: e}
+	if err := d.addConstraint(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// RemoveConstraint removes the unique or check constraint, the foreign
+// key is removed by DataSet.RemoveForeignKey.
+func (d *DataTable) RemoveConstraint(name string) error {
+	for i, c := range d.constraints {
+		if c.Name() != name {
+			continue
+		}
+		switch c := c.(type) {
+		case *ForeignKeyConstraint:
+			return fmt.Errorf("the constraint [%s] is a foreign key", name)
+		case *UniqueConstraint:
+			d.DropIndex(c.indexName())
+		}
+		d.constraints = append(d.constraints[:i], d.constraints[i+1:]...)
+		return nil
+	}
+	return ConstraintNotFoundError(name)
+}
+
+// removeConstraint removes the constraint without checks.
+func (d *DataTable) removeConstraint(c tableConstraint) {
+	for i, v := range d.constraints {
+		if v == c {
+			d.constraints = append(d.constraints[:i], d.constraints[i+1:]...)
+			return
+		}
+	}
+}
+
+// ConstraintNames returns the names of the constraints of the table,
+// including the foreign keys of the relations the table is child of.
+func (d *DataTable) ConstraintNames() []string {
+	result := make([]string, len(d.constraints))
+	for i, c := range d.constraints {
+		result[i] = c.Name()
+	}
+	return result
+}
+
+// checkConstraints checks the new values of the row against the
+// constraints, rowIndex is -1 for the row being added.
+func (d *DataTable) checkConstraints(vs []interface{}, rowIndex int) error {
+	if d.constraintsDisabled || len(d.constraints) == 0 || len(vs) != d.ColumnCount() {
+		return nil
+	}
+	data, err := d.validValues(vs)
+	if err != nil {
+		//returned by addValues or setValues
+		return nil
+	}
+	values := d.decodeValues(data)
+	trueIndex := -1
+	if rowIndex > -1 {
+		trueIndex = d.primaryIndexes.trueIndex(rowIndex)
+	}
+	for _, c := range d.constraints {
+		if err := c.checkRow(values, trueIndex); err != nil {
+			err.RowIndex = rowIndex
+			return err
+		}
+	}
+	return nil
+}
+
+// violations returns the violations of the rows against the constraint.
+func (d *DataTable) violations(c tableConstraint) []*ConstraintError {
+	var result []*ConstraintError
+	for i := 0; i < d.RowCount(); i++ {
+		if err := c.checkRow(d.GetValues(i), d.primaryIndexes.trueIndex(i)); err != nil {
+			err.RowIndex = i
+			result = append(result, err)
+		}
+	}
+	return result
+}
+
+// DisableConstraints stops checking the constraints by AddValues and
+// SetValues, used by the bulk load.
+func (d *DataTable) DisableConstraints() {
+	d.constraintsDisabled = true
+}
+
+// EnableConstraints validates the rows against the constraints and
+// enables them. If some rows violate the constraints, a
+// *ConstraintViolationsError reports every violating row and the
+// constraints are kept disabled.
+func (d *DataTable) EnableConstraints() error {
+	if err := d.validateConstraints(); err != nil {
+		return err
+	}
+	d.constraintsDisabled = false
+	return nil
+}
+
+// validateConstraints returns a *ConstraintViolationsError if some rows
+// violate the constraints.
+func (d *DataTable) validateConstraints() error {
+	var errs []*ConstraintError
+	for _, c := range d.constraints {
+		errs = append(errs, d.violations(c)...)
+	}
+	if len(errs) > 0 {
+		return &ConstraintViolationsError{Violations: errs}
+	}
+	return nil
+}
+
+// bindConstraints binds the unique and check constraints to the columns
+// replaced, the constraints on the missing columns are removed and the
+// indexes of the unique constraints are created. The rows are validated
+// unless the constraints are disabled.
+func (d *DataTable) bindConstraints() error {
+	var constraints []tableConstraint
+	for _, c := range d.constraints {
+		switch c := c.(type) {
+		case *UniqueConstraint:
+			missing := false
+			for _, col := range c.Columns {
+				missing = missing || d.ColumnIndex(col) == -1
+			}
+			if missing {
+				continue
+			}
+			if _, err := c.index(); err != nil {
+				return err
+			}
+		case *CheckConstraint:
+			e, err := compileFilter(d, c.Expression)
+			if err != nil {
+				continue
+			}
+			c.expr = e
+		}
+		constraints = append(constraints, c)
+	}
+	d.constraints = constraints
+	if d.constraintsDisabled {
+		return nil
+	}
+	return d.validateConstraints()
+}
+
+// ConstraintsEnabled reports whether the constraints are checked.
+func (d *DataTable) ConstraintsEnabled() bool {
+	return !d.constraintsDisabled
+}
@@ -0,0 +1,154 @@
+package datatable
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConstraints(t *testing.T) {
+	table := NewDataTable("items")
+	table.AddColumn(NewInt64Column("id"))
+	table.AddColumn(NewStringColumn("code"))
+	table.AddColumn(NewInt64Column("qty"))
+	table.AddColumn(NewFloat64Column("price"))
+	table.SetPK("id")
+	table.AddValues(int64(1), "a", int64(1), 1.5)
+	table.AddValues(int64(2), "b", int64(0), 2.0)
+	if _, err := table.AddUniqueConstraint("id"); err == nil {
+		t.Error("the primary key must be error")
+	}
+	uq, err := table.AddUniqueConstraint("code")
+	if err != nil || uq.Name() != "UQ_code" {
+		t.Fatal(err)
+	}
+	if _, err := table.AddCheckConstraint("CK_qty", "qty > 0"); err == nil {
+		t.Error("the existing row must be error")
+	}
+	if _, err := table.AddCheckConstraint("CK_qty", "qty >= 0 AND price > 0"); err != nil {
+		t.Fatal(err)
+	}
+	err = table.AddValues(int64(3), "a", int64(1), 1.0)
+	if cerr, ok := err.(*ConstraintError); !ok || cerr.Constraint != "UQ_code" || cerr.RowIndex != -1 || cerr.Key[0] != "a" {
+		t.Error(err)
+	}
+	err = table.SetValues(1, int64(2), "b", int64(-1), 2.0)
+	if cerr, ok := err.(*ConstraintError); !ok || cerr.Constraint != "CK_qty" || cerr.RowIndex != 1 || cerr.Key[0] != int64(2) {
+		t.Error(err)
+	}
+	//the row itself is not duplicate
+	if err := table.SetValues(1, int64(2), "b", int64(5), 2.0); err != nil {
+		t.Error(err)
+	}
+	if table.RowCount() != 2 || table.GetValue(1, 2) != int64(5) {
+		t.Error(table.AsCsv())
+	}
+	//bulk load
+	table.DisableConstraints()
+	table.AddValues(int64(3), "a", int64(1), 1.0)
+	table.AddValues(int64(4), "c", int64(-1), 1.0)
+	table.AddValues(int64(5), "d", int64(1), 0.0)
+	err = table.EnableConstraints()
+	verr, ok := err.(*ConstraintViolationsError)
+	if !ok || len(verr.Violations) != 4 || table.ConstraintsEnabled() {
+		t.Fatal(err)
+	}
+	var rows []int
+	for _, v := range verr.Violations {
+		rows = append(rows, v.RowIndex)
+	}
+	if len(rows) != 4 || rows[0] != 0 || rows[1] != 2 || rows[2] != 3 || rows[3] != 4 {
+		t.Error(rows)
+	}
+	table.DeleteRow(4)
+	table.DeleteRow(3)
+	table.DeleteRow(2)
+	if err := table.EnableConstraints(); err != nil || !table.ConstraintsEnabled() {
+		t.Error(err)
+	}
+	if err := table.RemoveConstraint("UQ_code"); err != nil {
+		t.Error(err)
+	}
+	if err := table.AddValues(int64(3), "a", int64(1), 1.0); err != nil {
+		t.Error(err)
+	}
+	if len(table.IndexNames()) != 0 || len(table.ConstraintNames()) != 1 {
+		t.Error(table.IndexNames(), table.ConstraintNames())
+	}
+	if err := table.RemoveConstraint("UQ_code"); err == nil {
+		t.Error("must be error")
+	}
+}
+
+func TestConstraintsReplaceColumns(t *testing.T) {
+	//the constraint on the column added after the savepoint is removed
+	table := NewDataTable("items")
+	table.AddColumn(NewInt64Column("id"))
+	sp := table.Begin()
+	table.AddColumn(NewInt64Column("qty"))
+	if _, err := table.AddCheckConstraint("ck", "qty >= 0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.RollbackTo(sp); err != nil || len(table.ConstraintNames()) != 0 {
+		t.Error(err, table.ConstraintNames())
+	}
+	if err := table.AddValues(int64(1)); err != nil {
+		t.Error(err)
+	}
+	//the check is bound to the columns read
+	table = NewDataTable("items")
+	table.AddColumn(NewInt64Column("id"))
+	table.AddColumn(NewInt64Column("qty"))
+	if _, err := table.AddCheckConstraint("ck", "qty >= 0"); err != nil {
+		t.Fatal(err)
+	}
+	table.AddValues(int64(1), int64(1))
+	src := NewDataTable("items")
+	src.AddColumn(NewInt64Column("qty"))
+	src.AddColumn(NewInt64Column("id"))
+	src.AddValues(int64(2), int64(1))
+	bys, err := json.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(bys, table); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.AddValues(int64(-5), int64(1)); err == nil {
+		t.Error("must be error")
+	}
+	//the rows read violating the constraints are rejected
+	src.SetValues(0, int64(-2), int64(1))
+	if bys, err = json.Marshal(src); err != nil {
+		t.Fatal(err)
+	}
+	want := table.AsCsv()
+	err = json.Unmarshal(bys, table)
+	if _, ok := err.(*ConstraintViolationsError); !ok || table.AsCsv() != want {
+		t.Error(err, table.AsCsv())
+	}
+	if err := table.AddValues(int64(-5), int64(1)); err == nil {
+		t.Error("must be error")
+	}
+}
+
+func TestUniqueConstraintIndex(t *testing.T) {
+	table := NewDataTable("items")
+	table.AddColumn(NewInt64Column("id"))
+	table.AddColumn(NewStringColumn("code"))
+	table.SetPK("id")
+	uq, err := table.AddUniqueConstraint("code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the index is created by AddUniqueConstraint
+	if names := table.IndexNames(); len(names) != 1 || names[0] != uq.indexName() {
+		t.Error(names)
+	}
+	//the index can't be created, the check fails instead of passing
+	bad := &UniqueConstraint{Columns: []string{"missing"}, table: table, name: "UQ_missing"}
+	table.constraints = append(table.constraints, bad)
+	err = table.AddValues(int64(1), "a")
+	if cerr, ok := err.(*ConstraintError); !ok || cerr.Constraint != "UQ_missing" || table.RowCount() != 0 {
+		t.Error(err)
+	}
+}
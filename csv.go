@@ -0,0 +1,289 @@
+package datatable
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ByteaEncoding is the text encoding of bytea values.
+type ByteaEncoding int
+
+const (
+	//ByteaHex is the postgres hex format \x0102,same as EncodeString
+	ByteaHex ByteaEncoding = iota
+	ByteaBase64
+)
+
+// WriteCsvOptions are the options of WriteCsv and WriteTsv, the zero
+// value writes the same text as AsCsv.
+type WriteCsvOptions struct {
+	//the field delimiter, default is ',' for CSV and '\t' for TSV
+	Comma rune
+	//don't write the header line
+	NoHeader bool
+	//the text of null, default is empty
+	NullString string
+	//the layout of time, default is time.RFC3339Nano
+	TimeFormat string
+	//the format of float as strconv.FormatFloat, e.g. 'f', 'e' or 'g',
+	//0 writes the float as EncodeString
+	FloatFormat byte
+	//the precision of float with FloatFormat as strconv.FormatFloat, -1
+	//is the smallest number of digits necessary
+	FloatPrecision int
+	ByteaEncoding  ByteaEncoding
+	//the columns to write, all columns if empty
+	Columns []string
+}
+
+func (o *WriteCsvOptions) format(col *DataColumn, v interface{}) string {
+	switch tv := v.(type) {
+	case nil:
+		return o.NullString
+	case float64:
+		if o.FloatFormat != 0 {
+			return strconv.FormatFloat(tv, o.FloatFormat, o.FloatPrecision, 64)
+		}
+	case time.Time:
+		if o.TimeFormat != "" {
+			return tv.Format(o.TimeFormat)
+		}
+	case []byte:
+		if o.ByteaEncoding == ByteaBase64 {
+			return base64.StdEncoding.EncodeToString(tv)
+		}
+	}
+	return col.EncodeString(v)
+}
+
+// WriteCsv writes the rows as CSV to w row by row, returns the error of
+// writing.
+func (d *DataTable) WriteCsv(w io.Writer, opts *WriteCsvOptions) error {
+	if opts == nil {
+		opts = &WriteCsvOptions{}
+	}
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = d.ColumnNames()
+	}
+	if !strings.ContainsRune("\x00beEfgGxX", rune(opts.FloatFormat)) {
+		return fmt.Errorf("invalid float format %q", opts.FloatFormat)
+	}
+	colIdx := make([]int, len(columns))
+	for i, c := range columns {
+		if colIdx[i] = d.ColumnIndex(c); colIdx[i] == -1 {
+			return ColumnNotFoundError(c)
+		}
+	}
+	writer := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		writer.Comma = opts.Comma
+	}
+	if !opts.NoHeader {
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+	}
+	line := make([]string, len(columns))
+	for rowIdx := 0; rowIdx < d.RowCount(); rowIdx++ {
+		for i, c := range colIdx {
+			line[i] = opts.format(d.Columns[c], d.GetValue(rowIdx, c))
+		}
+		if err := writer.Write(line); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteTsv writes the rows as tab separated text to w, same as WriteCsv
+// with the '\t' delimiter.
+func (d *DataTable) WriteTsv(w io.Writer, opts *WriteCsvOptions) error {
+	o := WriteCsvOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Comma == 0 {
+		o.Comma = '\t'
+	}
+	return d.WriteCsv(w, &o)
+}
+
+// ReadCsvOptions are the options of ReadCsv.
+type ReadCsvOptions struct {
+	//the field delimiter, default is ','
+	Comma rune
+	//load the rows as UNCHANGE instead of INSERT
+	Unchanged bool
+}
+
+// ReadCsv reads the CSV written by AsCsv into the table. The header line
+// maps the fields onto the columns, a column not in the header gets the
+// auto increment or the DefaultValue, else the zero value. When the table
+// has no column, the columns are created from the header and their types
+// are inferred from the values. The values are decoded by
+// DataColumn.DecodeString. On error, the table is rolled back by a
+// savepoint, no row of the CSV is kept, so the rows existed are copied
+// once by Begin.
+func (d *DataTable) ReadCsv(r io.Reader, opts *ReadCsvOptions) error {
+	sp := d.Begin()
+	err := d.readCsv(r, opts)
+	if err != nil {
+		d.RollbackTo(sp)
+	}
+	d.Release(sp)
+	return err
+}
+func (d *DataTable) readCsv(r io.Reader, opts *ReadCsvOptions) error {
+	if opts == nil {
+		opts = &ReadCsvOptions{}
+	}
+	reader := csv.NewReader(r)
+	if opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	//lines are the line numbers of the fields
+	read := func() ([]string, []int, error) {
+		record, err := reader.Read()
+		if err != nil {
+			return nil, nil, err
+		}
+		lines := make([]int, len(record))
+		for i := range record {
+			lines[i], _ = reader.FieldPos(i)
+		}
+		return record, lines, nil
+	}
+	next := read
+	if d.ColumnCount() == 0 {
+		//read all records to infer the columns
+		var records [][]string
+		var lines [][]int
+		for {
+			record, line, err := read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			records = append(records, record)
+			lines = append(lines, line)
+		}
+		if err := d.inferCsvColumns(header, records); err != nil {
+			return err
+		}
+		next = func() ([]string, []int, error) {
+			if len(records) == 0 {
+				return nil, nil, io.EOF
+			}
+			record, line := records[0], lines[0]
+			records, lines = records[1:], lines[1:]
+			return record, line, nil
+		}
+	}
+	colIdx := make([]int, len(header))
+	for i, name := range header {
+		if colIdx[i] = d.ColumnIndex(name); colIdx[i] == -1 {
+			return fmt.Errorf("line 1, column %q: %v", name, ColumnNotFoundError(name))
+		}
+	}
+	changed := d.changed
+	for {
+		record, lines, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		values := d.missingValues()
+		for i, s := range record {
+			col := d.Columns[colIdx[i]]
+			if s == "" && col.NotNull && col.DataType == String {
+				values[colIdx[i]] = ""
+				continue
+			}
+			if values[colIdx[i]], err = col.DecodeString(s); err != nil {
+				return fmt.Errorf("line %d, column %q: %v", lines[i], header[i], err)
+			}
+		}
+		if err := d.AddValues(values...); err != nil {
+			return fmt.Errorf("line %d: %v", lines[0], err)
+		}
+		if opts.Unchanged {
+			d.setRowStatus(len(d.rowStatus)-1, UNCHANGE)
+		}
+	}
+	if opts.Unchanged {
+		d.changed = changed
+	}
+	return nil
+}
+
+// inferCsvColumns adds the columns of the header, the type is the first
+// of int64, float64, bool, time, bytea and string that can decode all
+// values, the column is nullable if has empty value. A column without
+// value is a nullable string.
+func (d *DataTable) inferCsvColumns(header []string, records [][]string) error {
+	for i, name := range header {
+		if d.ColumnIndex(name) > -1 {
+			return fmt.Errorf("line 1, column %q: %v", name, ColumnExistsError)
+		}
+		notNull, hasValue := true, false
+		candidates := []ColumnType{Int64, Float64, Bool, Time, Bytea}
+		for _, record := range records {
+			if i >= len(record) || record[i] == "" {
+				notNull = false
+				continue
+			}
+			hasValue = true
+			var left []ColumnType
+			for _, t := range candidates {
+				if csvValueIs(t, record[i]) {
+					left = append(left, t)
+				}
+			}
+			candidates = left
+		}
+		dataType := String
+		if hasValue && len(candidates) > 0 {
+			dataType = candidates[0]
+		}
+		d.AddColumn(NewDataColumn(name, dataType, 0, notNull))
+	}
+	return nil
+}
+func csvValueIs(t ColumnType, s string) bool {
+	var err error
+	switch t {
+	case Int64:
+		_, err = strconv.ParseInt(s, 10, 64)
+	case Float64:
+		_, err = strconv.ParseFloat(s, 64)
+	case Bool:
+		switch strings.ToLower(s) {
+		case "t", "f", "true", "false":
+		default:
+			return false
+		}
+	case Time:
+		_, err = time.Parse(time.RFC3339Nano, s)
+	case Bytea:
+		_, err = decodeHex(s)
+	}
+	return err == nil
+}
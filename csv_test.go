@@ -0,0 +1,154 @@
+package datatable
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadCsv(t *testing.T) {
+	src := CreateTestData()
+	src.AddColumn(TimeColumn("t", false))
+	src.AddColumn(ByteaColumn("b", false))
+	src.SetValues(0, append(src.GetValues(0)[:3], time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC), []byte{1, 2})...)
+
+	table := src.Clone()
+	if err := table.ReadCsv(strings.NewReader(src.AsCsv()), nil); err != nil {
+		t.Fatal(err)
+	}
+	if table.AsCsv() != src.AsCsv() || len(table.GetChange().InsertRows) != 5 {
+		t.Error(table.AsCsv())
+	}
+	//infer the columns
+	table = NewDataTable("table1")
+	if err := table.ReadCsv(strings.NewReader(src.AsCsv()), &ReadCsvOptions{Unchanged: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := []*DataColumn{
+		NewDataColumn("column1", String, 0, true),
+		NewDataColumn("column2", Int64, 0, true),
+		NewDataColumn("column3", String, 0, true),
+		NewDataColumn("t", Time, 0, false),
+		NewDataColumn("b", Bytea, 0, false),
+	}
+	for i, c := range want {
+		if c.Name != table.Columns[i].Name || c.DataType != table.Columns[i].DataType || c.NotNull != table.Columns[i].NotNull {
+			t.Error(table.Columns[i])
+		}
+	}
+	if table.RowCount() != 5 || table.HasChange() || table.GetChange().RowCount != 0 {
+		t.Error("error")
+	}
+	//only some columns,tab separated
+	table = src.Clone()
+	err := table.ReadCsv(strings.NewReader("column2\tcolumn1\n1\ta\n2\tb\n"), &ReadCsvOptions{Comma: '\t'})
+	if err != nil || table.RowCount() != 2 || table.Row(1)["column3"] != "" || table.Row(1)["t"] != nil {
+		t.Error(err)
+	}
+	table = src.Clone()
+	err = table.ReadCsv(strings.NewReader("column1,column2\na,1\n\"b\nc\",x\n"), nil)
+	if err == nil || err.Error() != `line 4, column "column2": strconv.ParseInt: parsing "x": invalid syntax` {
+		t.Error(err)
+	}
+	//the rows before the error are not kept
+	if table.RowCount() != 0 || table.HasChange() {
+		t.Error(table.AsCsv())
+	}
+	table = NewDataTable("table1")
+	if err = table.ReadCsv(strings.NewReader("a,b\n1,\n2,\n"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if table.Columns[1].DataType != String || table.Columns[1].NotNull || table.Row(0)["b"] != nil {
+		t.Error(table.Columns[1])
+	}
+	//the inferred columns are removed too
+	table = NewDataTable("table1")
+	table.OnRowChanging(func(e *RowChangeEvent) error {
+		if e.NewValues[0] == int64(2) {
+			return errors.New("veto")
+		}
+		return nil
+	})
+	if err = table.ReadCsv(strings.NewReader("a,b\n1,x\n2,y\n"), nil); err == nil || table.ColumnCount() != 0 || table.RowCount() != 0 {
+		t.Error(err)
+	}
+	table = src.Clone()
+	if err = table.ReadCsv(strings.NewReader("column1,nothing\n"), nil); err == nil {
+		t.Error("must be error")
+	}
+}
+func TestReadCsvMissingColumns(t *testing.T) {
+	table := NewDataTable("items")
+	id := NewInt64Column("id")
+	id.AutoIncrement = true
+	id.AutoIncrementSeed = 1
+	table.AddColumn(id)
+	table.AddColumn(NewStringColumn("name"))
+	status := NewStringColumn("status")
+	status.DefaultValue = "new"
+	table.AddColumn(status)
+	table.AddColumn(NewInt64Column("qty"))
+	table.SetPK("id")
+	//the missing columns are filled by the auto increment and the default
+	if err := table.ReadCsv(strings.NewReader("name\na\nb\n"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if table.AsCsv() != "id,name,status,qty\n1,a,new,0\n2,b,new,0\n" {
+		t.Error(table.AsCsv())
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+func TestWriteCsv(t *testing.T) {
+	table := NewDataTable("table1")
+	table.AddColumn(NewStringColumn("s"))
+	table.AddColumn(Float64Column("f", false))
+	table.AddColumn(TimeColumn("t", false))
+	table.AddColumn(ByteaColumn("b", false))
+	table.AddValues("a\tb", 1.25, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), []byte{1, 2})
+	table.AddValues("c", nil, nil, nil)
+
+	bys := &bytes.Buffer{}
+	if err := table.WriteCsv(bys, nil); err != nil || bys.String() != table.AsCsv() {
+		t.Error(err, bys.String())
+	}
+	bys.Reset()
+	err := table.WriteCsv(bys, &WriteCsvOptions{
+		Comma:          ';',
+		NoHeader:       true,
+		NullString:     "NULL",
+		TimeFormat:     "2006-01-02",
+		FloatFormat:    'f',
+		FloatPrecision: 1,
+		ByteaEncoding:  ByteaBase64,
+		Columns:        []string{"b", "f", "t", "s"},
+	})
+	if want := "AQI=;1.2;2020-01-02;a\tb\nNULL;NULL;NULL;c\n"; err != nil || bys.String() != want {
+		t.Error(err, bys.String())
+	}
+	bys.Reset()
+	err = table.WriteTsv(bys, &WriteCsvOptions{FloatFormat: 'f', FloatPrecision: -1, Columns: []string{"f", "s"}})
+	if want := "f\ts\n1.25\t\"a\tb\"\n\tc\n"; err != nil || bys.String() != want {
+		t.Errorf("%v %q", err, bys.String())
+	}
+	bys.Reset()
+	err = table.WriteCsv(bys, &WriteCsvOptions{FloatFormat: 'f', NoHeader: true, Columns: []string{"f"}})
+	if want := "1\n\n"; err != nil || bys.String() != want {
+		t.Errorf("%v %q", err, bys.String())
+	}
+	if err := table.WriteCsv(bys, &WriteCsvOptions{FloatFormat: 'z'}); err == nil {
+		t.Error("must be error")
+	}
+	if err := table.WriteCsv(failWriter{}, nil); err == nil || err.Error() != "disk full" {
+		t.Error(err)
+	}
+	if err := table.WriteCsv(bys, &WriteCsvOptions{Columns: []string{"nothing"}}); err == nil {
+		t.Error("must be error")
+	}
+}
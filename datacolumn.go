@@ -25,6 +25,23 @@ type DataColumn struct {
 	Name     string
 	MaxSize  int
 	NotNull  bool
+	//the decoded value filled by AddValues and AddRow for the nil or
+	//missing value, nil for none
+	DefaultValue interface{}
+	//the int64 column is filled with the increasing values from the seed
+	//by the step, like the .Net AutoIncrementSeed and AutoIncrementStep.
+	//The negative seed and step make the temporary ids of the new rows
+	AutoIncrement     bool
+	AutoIncrementSeed int64
+	AutoIncrementStep int64
+	//the expression of the computed column as DataTable.Select, e.g.
+	//"qty * price", it can use the columns before it. The column is read
+	//only, the value is recalculated by AddValues and SetValues
+	Expression string
+	expr       *expression
+	//the next value of the auto increment, valid when autoIncrementUsed
+	autoIncrementNext int64
+	autoIncrementUsed bool
 }
 
 var reflectType map[ColumnType]reflect.Type = map[ColumnType]reflect.Type{
@@ -95,9 +112,53 @@ func (d *DataColumn) ZeroValue() interface{} {
 		return typeNil[d.DataType]
 	}
 }
+
+// defaultValue returns the decoded default value of the column, the zero
+// value if no DefaultValue.
+func (d *DataColumn) defaultValue() interface{} {
+	if d.DefaultValue != nil {
+		return d.DefaultValue
+	}
+	return d.Decode(d.ZeroValue())
+}
+func (d *DataColumn) autoIncrementStep() int64 {
+	if d.AutoIncrementStep == 0 {
+		return 1
+	}
+	return d.AutoIncrementStep
+}
+
+// nextAutoIncrement returns the next value of the auto increment.
+func (d *DataColumn) nextAutoIncrement() int64 {
+	if !d.autoIncrementUsed {
+		d.autoIncrementNext = d.AutoIncrementSeed
+		d.autoIncrementUsed = true
+	}
+	v := d.autoIncrementNext
+	d.autoIncrementNext += d.autoIncrementStep()
+	return v
+}
+
+// seeAutoIncrement moves the next value of the auto increment over the
+// value of a added row.
+func (d *DataColumn) seeAutoIncrement(v int64) {
+	next, step := d.AutoIncrementSeed, d.autoIncrementStep()
+	if d.autoIncrementUsed {
+		next = d.autoIncrementNext
+	}
+	if (step > 0 && v >= next) || (step < 0 && v <= next) {
+		d.autoIncrementNext = v + step
+		d.autoIncrementUsed = true
+	}
+}
+
+// Clone returns a copy of the column, the copy of the computed column is
+// a normal column holds the values.
 func (d *DataColumn) Clone() *DataColumn {
 	result := DataColumn{}
 	result = *d
+	result.Expression = ""
+	result.expr = nil
 	return &result
 }
 func (d *DataColumn) StoreType() reflect.Type {
@@ -62,3 +62,11 @@ func (r *dataRows) SetRow(row int, values []interface{}) {
 	}
 	return
 }
+
+// Delete removes the row and keeps the order of the rest rows.
+func (r *dataRows) Delete(rowIndex int) {
+	for i, _ := range r.data {
+		v := reflect.ValueOf(r.data[i])
+		r.data[i] = reflect.AppendSlice(v.Slice(0, rowIndex), v.Slice(rowIndex+1, v.Len())).Interface()
+	}
+}
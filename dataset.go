@@ -0,0 +1,305 @@
+package datatable
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	TableExistsError    = errors.New("the table exists")
+	RelationExistsError = errors.New("the relation exists")
+)
+
+// TableNotFoundError returns the error of the table not found.
+func TableNotFoundError(name string) error {
+	return fmt.Errorf("the table [%s] not found", name)
+}
+
+// RelationNotFoundError returns the error of the relation not found.
+func RelationNotFoundError(name string) error {
+	return fmt.Errorf("the relation [%s] not found", name)
+}
+
+// DataRelation links the primary key of the parent table to the columns
+// of the child table.
+type DataRelation struct {
+	Name         string
+	ParentTable  *DataTable
+	ChildTable   *DataTable
+	ChildColumns []string
+	//the name of the index on the child columns
+	indexName  string
+	foreignKey *ForeignKeyConstraint
+}
+
+// childIndex returns the index on the child columns, it is created again
+// if dropped.
+func (r *DataRelation) childIndex() (*dataIndex, error) {
+	if x := r.ChildTable.indexByName(r.indexName); x != nil {
+		return x, nil
+	}
+	if err := r.ChildTable.CreateIndex(r.indexName, r.ChildColumns, false); err != nil {
+		return nil, err
+	}
+	return r.ChildTable.indexByName(r.indexName), nil
+}
+
+// childKey returns the values of the child columns of the child row.
+func (r *DataRelation) childKey(childRowIndex int) []interface{} {
+	result := make([]interface{}, len(r.ChildColumns))
+	for i, c := range r.ChildColumns {
+		result[i] = r.ChildTable.GetValue(childRowIndex, r.ChildTable.ColumnIndex(c))
+	}
+	return result
+}
+
+// DataSet holds the tables and the relations between them, like the .Net
+// DataSet.
+type DataSet struct {
+	Name      string
+	tables    []*DataTable
+	relations []*DataRelation
+}
+
+func NewDataSet(name string) *DataSet {
+	return &DataSet{Name: name}
+}
+
+// AddTable adds the table, the name of the table must be unique.
+func (s *DataSet) AddTable(table *DataTable) error {
+	if s.Table(table.TableName) != nil {
+		return TableExistsError
+	}
+	s.tables = append(s.tables, table)
+	return nil
+}
+
+// Table returns the table by name, nil if not found.
+func (s *DataSet) Table(name string) *DataTable {
+	for _, t := range s.tables {
+		if t.TableName == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Tables returns the tables in order of adding.
+func (s *DataSet) Tables() []*DataTable {
+	return s.tables
+}
+
+// RemoveTable removes the table and the relations of it.
+func (s *DataSet) RemoveTable(name string) error {
+	for i, t := range s.tables {
+		if t.TableName != name {
+			continue
+		}
+		for _, r := range append([]*DataRelation{}, s.relations...) {
+			if r.ParentTable == t || r.ChildTable == t {
+				s.RemoveRelation(r.Name)
+			}
+		}
+		s.tables = append(s.tables[:i], s.tables[i+1:]...)
+		return nil
+	}
+	return TableNotFoundError(name)
+}
+
+// AddRelation adds a relation between the primary key of the parent
+// table and the child columns, an index on the child columns is created
+// for the navigation.
+func (s *DataSet) AddRelation(name, parentTable, childTable string, childColumns ...string) (*DataRelation, error) {
+	if s.Relation(name) != nil {
+		return nil, RelationExistsError
+	}
+	parent, child := s.Table(parentTable), s.Table(childTable)
+	if parent == nil {
+		return nil, TableNotFoundError(parentTable)
+	}
+	if child == nil {
+		return nil, TableNotFoundError(childTable)
+	}
+	if !parent.HasPrimaryKey() {
+		return nil, NoPrimaryKeyError
+	}
+	if len(childColumns) != len(parent.PK) {
+		return nil, fmt.Errorf("the relation [%s] has %d child columns, the primary key has %d", name, len(childColumns), len(parent.PK))
+	}
+	for i, c := range childColumns {
+		j := child.ColumnIndex(c)
+		if j == -1 {
+			return nil, ColumnNotFoundError(c)
+		}
+		if pt := parent.Columns[parent.ColumnIndex(parent.PK[i])].DataType; child.Columns[j].DataType != pt {
+			return nil, fmt.Errorf("the column [%s] type %s not equal the primary key type %s", c, child.Columns[j].DataType, pt)
+		}
+	}
+	r := &DataRelation{
+		Name:         name,
+		ParentTable:  parent,
+		ChildTable:   child,
+		ChildColumns: childColumns,
+		indexName:    "relation:" + name,
+	}
+	if _, err := r.childIndex(); err != nil {
+		return nil, err
+	}
+	s.relations = append(s.relations, r)
+	return r, nil
+}
+
+// Relation returns the relation by name, nil if not found.
+func (s *DataSet) Relation(name string) *DataRelation {
+	for _, r := range s.relations {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Relations returns the relations in order of adding.
+func (s *DataSet) Relations() []*DataRelation {
+	return s.relations
+}
+
+// RemoveRelation removes the relation and the index of it.
+func (s *DataSet) RemoveRelation(name string) error {
+	for i, r := range s.relations {
+		if r.Name == name {
+			s.RemoveForeignKey(name)
+			r.ChildTable.DropIndex(r.indexName)
+			s.relations = append(s.relations[:i], s.relations[i+1:]...)
+			return nil
+		}
+	}
+	return RelationNotFoundError(name)
+}
+
+// ChildRows returns the row indexes of the child table rows related to
+// the parent row.
+func (s *DataSet) ChildRows(relation string, parentRowIndex int) ([]int, error) {
+	r := s.Relation(relation)
+	if r == nil {
+		return nil, RelationNotFoundError(relation)
+	}
+	if parentRowIndex < 0 || parentRowIndex >= r.ParentTable.RowCount() {
+		return nil, RowNotFoundError
+	}
+	x, err := r.childIndex()
+	if err != nil {
+		return nil, err
+	}
+	return x.rowIndexes(r.ParentTable.KeyValues(parentRowIndex)), nil
+}
+
+// ParentRow returns the row index of the parent table row related to the
+// child row, -1 if not found or the child columns have null.
+func (s *DataSet) ParentRow(relation string, childRowIndex int) (int, error) {
+	r := s.Relation(relation)
+	if r == nil {
+		return -1, RelationNotFoundError(relation)
+	}
+	if childRowIndex < 0 || childRowIndex >= r.ChildTable.RowCount() {
+		return -1, RowNotFoundError
+	}
+	key := r.childKey(childRowIndex)
+	for _, v := range key {
+		if v == nil {
+			return -1, nil
+		}
+	}
+	return r.ParentTable.Find(key...), nil
+}
+
+// DataSetChange is the changed rows of a table, Kind is INSERT, UPDATE
+// or DELETE.
+type DataSetChange struct {
+	Table *DataTable
+	Kind  byte
+	Rows  []*ChangeRow
+}
+
+// sortedTables returns the tables ordered so the parent table is before
+// the child table, the relation to the table itself is ignored.
+func (s *DataSet) sortedTables() ([]*DataTable, error) {
+	var result []*DataTable
+	//0 unvisited,1 visiting,2 done
+	state := map[*DataTable]int{}
+	var visit func(t *DataTable) error
+	visit = func(t *DataTable) error {
+		switch state[t] {
+		case 1:
+			return fmt.Errorf("the relations of table [%s] have a cycle", t.TableName)
+		case 2:
+			return nil
+		}
+		state[t] = 1
+		for _, r := range s.relations {
+			if r.ChildTable == t && r.ParentTable != t {
+				if err := visit(r.ParentTable); err != nil {
+					return err
+				}
+			}
+		}
+		state[t] = 2
+		result = append(result, t)
+		return nil
+	}
+	for _, t := range s.tables {
+		if err := visit(t); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// GetChanges returns the changes of all tables in the order to apply:
+// the inserts and the updates of the parent tables before the child
+// tables, so a child row can reference a new parent row, then the deletes
+// of the child tables before the parent tables, so a child row moved away
+// from a deleted parent row is updated first. A key deleted and inserted
+// again in the same table is inserted before it is deleted, so the rows
+// reusing the deleted keys must be applied by the caller another way.
+func (s *DataSet) GetChanges() ([]*DataSetChange, error) {
+	tables, err := s.sortedTables()
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]*TableChange, len(tables))
+	for i, t := range tables {
+		changes[i] = t.GetChange()
+	}
+	result := []*DataSetChange{}
+	add := func(t *DataTable, kind byte, rows []*ChangeRow) {
+		if len(rows) > 0 {
+			result = append(result, &DataSetChange{Table: t, Kind: kind, Rows: rows})
+		}
+	}
+	for i, t := range tables {
+		add(t, INSERT, changes[i].InsertRows)
+		add(t, UPDATE, changes[i].UpdateRows)
+	}
+	for i := len(tables) - 1; i >= 0; i-- {
+		add(tables[i], DELETE, changes[i].DeleteRows)
+	}
+	return result, nil
+}
+
+// HasChanges reports whether any table has changes.
+func (s *DataSet) HasChanges() bool {
+	for _, t := range s.tables {
+		if t.HasChange() {
+			return true
+		}
+	}
+	return false
+}
+
+// AcceptChanges accepts the changes of all tables.
+func (s *DataSet) AcceptChanges() {
+	for _, t := range s.tables {
+		t.AcceptChange()
+	}
+}
@@ -0,0 +1,120 @@
+package datatable
+
+import (
+	"strings"
+	"testing"
+)
+
+func createOrderDataSet() *DataSet {
+	orders := NewDataTable("orders")
+	orders.AddColumn(NewInt64Column("id"))
+	orders.AddColumn(NewStringColumn("customer"))
+	orders.SetPK("id")
+	orders.AddValues(int64(1), "a")
+	orders.AddValues(int64(2), "b")
+	orders.AcceptChange()
+	lines := NewDataTable("lines")
+	lines.AddColumn(NewInt64Column("id"))
+	lines.AddColumn(Int64Column("order_id", false))
+	lines.AddColumn(NewStringColumn("product"))
+	lines.SetPK("id")
+	lines.AddValues(int64(1), int64(1), "x")
+	lines.AddValues(int64(2), int64(2), "y")
+	lines.AddValues(int64(3), int64(1), "z")
+	lines.AddValues(int64(4), nil, "w")
+	lines.AcceptChange()
+	ds := NewDataSet("ds")
+	//add the child first,the changes must be ordered by the relation
+	ds.AddTable(lines)
+	ds.AddTable(orders)
+	if _, err := ds.AddRelation("order_lines", "orders", "lines", "order_id"); err != nil {
+		panic(err)
+	}
+	return ds
+}
+func TestDataSet(t *testing.T) {
+	ds := createOrderDataSet()
+	orders, lines := ds.Table("orders"), ds.Table("lines")
+	if err := ds.AddTable(NewDataTable("orders")); err != TableExistsError {
+		t.Error(err)
+	}
+	if _, err := ds.AddRelation("bad", "orders", "lines", "product"); err == nil {
+		t.Error("must be error")
+	}
+	rows, err := ds.ChildRows("order_lines", orders.Find(int64(1)))
+	if err != nil || len(rows) != 2 || lines.GetValue(rows[0], 0) != int64(1) || lines.GetValue(rows[1], 0) != int64(3) {
+		t.Error(rows, err)
+	}
+	if i, err := ds.ParentRow("order_lines", lines.Find(int64(2))); err != nil || orders.GetValue(i, 0) != int64(2) {
+		t.Error(i, err)
+	}
+	if i, err := ds.ParentRow("order_lines", lines.Find(int64(4))); err != nil || i != -1 {
+		t.Error(i, err)
+	}
+	if _, err := ds.ChildRows("nothing", 0); err == nil {
+		t.Error("must be error")
+	}
+	//the index is maintained
+	lines.SetValues(lines.Find(int64(4)), int64(4), int64(2), "w")
+	if rows, _ := ds.ChildRows("order_lines", orders.Find(int64(2))); len(rows) != 2 {
+		t.Error(rows)
+	}
+	//changes
+	orders.AddValues(int64(3), "c")
+	lines.AddValues(int64(5), int64(3), "v")
+	lines.DeleteRow(lines.Find(int64(2)))
+	orders.DeleteRow(orders.Find(int64(2)))
+	changes, err := ds.GetChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		table string
+		kind  byte
+	}{{"orders", INSERT}, {"lines", INSERT}, {"lines", UPDATE}, {"lines", DELETE}, {"orders", DELETE}}
+	if len(changes) != len(want) {
+		t.Fatal(len(changes))
+	}
+	for i, w := range want {
+		if changes[i].Table.TableName != w.table || changes[i].Kind != w.kind {
+			t.Error(i, changes[i].Table.TableName, changes[i].Kind)
+		}
+	}
+	ds.AcceptChanges()
+	if ds.HasChanges() {
+		t.Error("error")
+	}
+	if err := ds.RemoveTable("orders"); err != nil || len(ds.Relations()) != 0 || len(lines.IndexNames()) != 0 {
+		t.Error(err)
+	}
+}
+
+// changeKinds returns the table and the kind of the changes.
+func changeKinds(changes []*DataSetChange) []string {
+	result := make([]string, len(changes))
+	for i, c := range changes {
+		result[i] = c.Table.TableName + ":" + string("-UID"[c.Kind])
+	}
+	return result
+}
+func TestDataSetChangesOrder(t *testing.T) {
+	//a line moved to a new order, the order is inserted before the update
+	ds := createOrderDataSet()
+	orders, lines := ds.Table("orders"), ds.Table("lines")
+	orders.AddValues(int64(3), "c")
+	lines.SetValues(lines.Find(int64(1)), int64(1), int64(3), "x")
+	changes, err := ds.GetChanges()
+	if got := changeKinds(changes); err != nil || strings.Join(got, ",") != "orders:I,lines:U" {
+		t.Error(got, err)
+	}
+	//a line moved off an order deleted, the line is updated before the
+	//delete
+	ds = createOrderDataSet()
+	orders, lines = ds.Table("orders"), ds.Table("lines")
+	lines.SetValues(lines.Find(int64(2)), int64(2), int64(1), "y")
+	orders.DeleteRow(orders.Find(int64(2)))
+	changes, err = ds.GetChanges()
+	if got := changeKinds(changes); err != nil || strings.Join(got, ",") != "lines:U,orders:D" {
+		t.Error(got, err)
+	}
+}
@@ -3,7 +3,6 @@ package datatable
 
 import (
 	"bytes"
-	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -19,6 +18,19 @@ const (
 	DELETE
 )
 
+// MissingSchemaAction specifies how Merge handles the source columns
+// that not exist in the target table.
+type MissingSchemaAction int
+
+const (
+	// MissingSchemaAdd adds the missing columns to the target table.
+	MissingSchemaAdd MissingSchemaAction = iota
+	// MissingSchemaIgnore ignores the missing columns.
+	MissingSchemaIgnore
+	// MissingSchemaError makes Merge return an error.
+	MissingSchemaError
+)
+
 var (
 	ColumnExistsError = errors.New("the column exists")
 	RowNotFoundError  = errors.New("the row not found")
@@ -67,6 +79,22 @@ type DataTable struct {
 	rowStatus      []byte
 	originData     [][]interface{}
 	deleteRows     *dataRows
+	indexes        []*dataIndex
+	//increased by every change of rows or columns
+	version int
+	//the count of the rows whose status is not UNCHANGE
+	changedRows int
+	savepoints  []*savepoint
+	savepointID int
+	history     history
+	events      tableEvents
+	constraints []tableConstraint
+	//the constraints are not checked by AddValues and SetValues
+	constraintsDisabled bool
+	//the foreign keys the table is the parent of
+	foreignKeys []*ForeignKeyConstraint
+	//the journal of the foreign key rules being applied
+	journal *journal
 }
 
 func NewDataTable(name string) *DataTable {
@@ -79,9 +107,40 @@ func NewDataTable(name string) *DataTable {
 	return d
 }
 
+// replaceWith replaces the table with the src table in place, the
+// constraints are bound to the columns of src. If the rows of src violate
+// the constraints, the table is kept and a *ConstraintViolationsError is
+// returned.
+func (d *DataTable) replaceWith(src *DataTable) error {
+	old := *d
+	version, events, depth := d.version, d.events, d.history.depth
+	constraints, disabled, foreignKeys := d.constraints, d.constraintsDisabled, d.foreignKeys
+	*d = *src
+	d.events = events
+	d.constraints, d.constraintsDisabled, d.foreignKeys = constraints, disabled, foreignKeys
+	d.history.depth = depth
+	d.primaryIndexes.dataTable = d
+	for _, x := range d.indexes {
+		x.dataTable = d
+	}
+	d.version = version + 1
+	d.history.clear()
+	if err := d.bindConstraints(); err != nil {
+		*d = old
+		d.bindConstraints()
+		return err
+	}
+	return nil
+}
+
 func (d *DataTable) AddColumn(c *DataColumn) *DataColumn {
 
 	if i := d.ColumnIndex(c.Name); i == -1 {
+		if c.IsComputed() {
+			if err := c.compileExpression(d); err != nil {
+				panic(err)
+			}
+		}
 		d.currentRows.AddColumn(c.StoreType())
 		d.deleteRows.AddColumn(c.StoreType())
 		for i := 0; i < len(d.originData); i++ {
@@ -89,6 +148,11 @@ func (d *DataTable) AddColumn(c *DataColumn) *DataColumn {
 		}
 		c.index = len(d.Columns)
 		d.Columns = append(d.Columns, c)
+		if c.IsComputed() {
+			d.fillComputed(c)
+		}
+		d.version++
+		d.recordHistory(&historyEntry{op: historyColumn, column: c})
 		return c
 	} else {
 		panic(ColumnExistsError)
@@ -122,25 +186,91 @@ func (d *DataTable) ColumnNames() []string {
 	return r
 }
 
+// KeyValues returns the decoded primary key values of the row, nil if
+// the table has no primary key.
 func (d *DataTable) KeyValues(rowIndex int) []interface{} {
 	if len(d.PK) == 0 {
 		return nil
 	}
 	var result []interface{}
 	for _, c := range d.PK {
-		result = append(result, d.currentRows.Get(d.ColumnIndex(c), d.primaryIndexes.trueIndex(rowIndex)))
+		i := d.ColumnIndex(c)
+		result = append(result, d.Columns[i].Decode(d.currentRows.Get(i, d.primaryIndexes.trueIndex(rowIndex))))
 	}
 	return result
 }
 func (d *DataTable) AcceptChange() {
-
+	events := d.commitEvents()
+	d.acceptChange()
+	for _, e := range events {
+		d.events.fireChanged(d.events.rowChanged, e)
+	}
+}
+func (d *DataTable) acceptChange() {
 	d.rowStatus = make([]byte, d.currentRows.Count())
+	d.changedRows = 0
 	d.originData = make([][]interface{}, d.currentRows.Count())
 	d.deleteRows = &dataRows{}
 	for _, c := range d.Columns {
 		d.deleteRows.AddColumn(c.StoreType())
 	}
 	d.changed = false
+	d.version++
+	d.history.clear()
+}
+
+// RejectChanges rolls the table back to the state of the last
+// AcceptChange: the inserted rows are removed, the updated rows are
+// restored from the origin data and the deleted rows are added back.
+func (d *DataTable) RejectChanges() {
+	rows := &dataRows{}
+	for _, c := range d.Columns {
+		rows.AddColumn(c.StoreType())
+	}
+	for i, status := range d.rowStatus {
+		switch status {
+		case UNCHANGE:
+			rows.AddRow(d.currentRows.GetRow(i))
+		case UPDATE:
+			rows.AddRow(d.originData[i])
+		}
+	}
+	rows.Merge(d.deleteRows)
+	d.currentRows = rows
+	d.primaryIndexes.rebuildPKIndex()
+	for _, x := range d.indexes {
+		x.rebuild()
+	}
+	d.acceptChange()
+}
+
+// RejectRowChanges rolls the row back to the state of the last
+// AcceptChange, the inserted row is removed and the updated row is
+// restored from the origin data. It returns KeyValueExists if the origin
+// key is used by another row now.
+func (d *DataTable) RejectRowChanges(rowIndex int) error {
+	if rowIndex < 0 || rowIndex >= d.RowCount() {
+		return RowNotFoundError
+	}
+	trueIndex := d.primaryIndexes.trueIndex(rowIndex)
+	switch d.rowStatus[trueIndex] {
+	case INSERT:
+		return d.DeleteRow(rowIndex)
+	case UPDATE:
+		if err := d.SetValues(rowIndex, d.decodeValues(d.originData[trueIndex])...); err != nil {
+			return err
+		}
+		d.setRowStatus(trueIndex, UNCHANGE)
+		d.originData[trueIndex] = nil
+		d.updateChanged()
+	}
+	return nil
+}
+
+// updateChanged sets the changed flag by the row status and the deleted
+// rows.
+func (d *DataTable) updateChanged() {
+	d.changed = d.deleteRows.Count() > 0 || d.changedRows > 0
 }
 
 func (d *DataTable) RowCount() int {
@@ -153,17 +283,17 @@ func (d *DataTable) GetValues(rowIndex int) []interface{} {
 	}
 	return rev
 }
-func (d *DataTable) getSequenceValues(r map[string]interface{}) []interface{} {
+// getSequenceValues returns the values of the map in the column order, a
+// missing column is filled by the value of fill, nil if fill is nil.
+func (d *DataTable) getSequenceValues(r map[string]interface{}, fill []interface{}) []interface{} {
 	vals := make([]interface{}, d.ColumnCount())
 	for i, col := range d.Columns {
 		var ok bool
-		if vals[i], ok = r[col.Name]; !ok {
-			panic(fmt.Errorf("can't find column:[%s] at %v", col.Name, r))
+		if vals[i], ok = r[col.Name]; !ok && fill != nil {
+			vals[i] = fill[i]
 		}
-
 	}
 	return vals
-
 }
 func (d *DataTable) getPkValues(values []interface{}) []interface{} {
 	var result []interface{}
@@ -173,16 +303,72 @@ func (d *DataTable) getPkValues(values []interface{}) []interface{} {
 	return result
 
 }
+
+// getPkStoreValues returns the decoded primary key values of a stored row.
+func (d *DataTable) getPkStoreValues(values []interface{}) []interface{} {
+	var result []interface{}
+	for _, c := range d.PK {
+		i := d.ColumnIndex(c)
+		result = append(result, d.Columns[i].Decode(values[i]))
+	}
+	return result
+}
 func (d *DataTable) ColumnCount() int {
 	return len(d.Columns)
 }
+// SetValues sets the values of the row, the values passed for the
+// computed columns are ignored and calculated again.
 func (d *DataTable) SetValues(rowIndex int, values ...interface{}) error {
-	newValues, err := d.validValues(values)
+	if rowIndex < 0 || rowIndex >= d.RowCount() {
+		return RowNotFoundError
+	}
+	values, err := d.computeValues(values)
 	if err != nil {
 		return err
 	}
-	if len(newValues) != d.ColumnCount() {
-		return NumberOfValueError(len(newValues), d.ColumnCount())
+	trueIndex := d.primaryIndexes.trueIndex(rowIndex)
+	var e *RowChangeEvent
+	if d.events.hasRowHandlers() {
+		e = &RowChangeEvent{Table: d, Action: RowChange, RowIndex: rowIndex, KeyValues: d.KeyValues(rowIndex),
+			OldValues: d.GetValues(rowIndex), NewValues: values}
+		if err := d.events.fireChanging(d.events.rowChanging, e); err != nil {
+			return err
+		}
+	}
+	if err := d.checkConstraints(values, rowIndex); err != nil {
+		return err
+	}
+	var entry *historyEntry
+	if d.journaling() {
+		entry = &historyEntry{op: historySet, trueIndex: trueIndex, before: d.rowStateOf(trueIndex)}
+	}
+	version := d.version
+	if err := d.setValues(rowIndex, values); err != nil || version == d.version {
+		return err
+	}
+	if entry != nil {
+		key, newKey := d.getPkStoreValues(entry.before.values), d.getPkStoreValues(d.currentRows.GetRow(trueIndex))
+		if err := d.cascade(entry, rowIndex, key, newKey); err != nil {
+			return err
+		}
+	}
+	d.recordHistory(entry)
+	if e != nil {
+		e.RowIndex = d.rowIndexOf(trueIndex)
+		e.KeyValues = d.KeyValues(e.RowIndex)
+		e.NewValues = d.GetValues(e.RowIndex)
+		d.events.fireColumnsChanged(e)
+		d.events.fireChanged(d.events.rowChanged, e)
+	}
+	return nil
+}
+func (d *DataTable) setValues(rowIndex int, values []interface{}) error {
+	if len(values) != d.ColumnCount() {
+		return NumberOfValueError(len(values), d.ColumnCount())
+	}
+	newValues, err := d.validValues(values)
+	if err != nil {
+		return err
 	}
 
 	trueIndex := d.primaryIndexes.trueIndex(rowIndex)
@@ -190,8 +376,8 @@ func (d *DataTable) SetValues(rowIndex int, values ...interface{}) error {
 	if reflect.DeepEqual(oldValues, newValues) {
 		return nil
 	}
-	oldPkValue := d.getPkValues(oldValues)
-	newPkValue := d.getPkValues(newValues)
+	oldPkValue := d.getPkStoreValues(oldValues)
+	newPkValue := d.getPkStoreValues(newValues)
 	pkChanged := false
 	var newKeyIndex int
 	if !reflect.DeepEqual(oldPkValue, newPkValue) {
@@ -202,10 +388,24 @@ func (d *DataTable) SetValues(rowIndex int, values ...interface{}) error {
 			return KeyValueExists
 		}
 	}
+	if err := d.checkIndexes(newValues, trueIndex); err != nil {
+		return err
+	}
+	var changedIndexes []*dataIndex
+	for _, x := range d.indexes {
+		if cmpValue(x.keyOfStore(oldValues), x.keyOfStore(newValues)) != 0 {
+			x.remove(trueIndex)
+			changedIndexes = append(changedIndexes, x)
+		}
+	}
 	d.changed = true
+	d.version++
 	d.currentRows.SetRow(trueIndex, newValues)
+	for _, x := range changedIndexes {
+		x.insert(trueIndex)
+	}
 	if d.rowStatus[trueIndex] == UNCHANGE {
-		d.rowStatus[trueIndex] = UPDATE
+		d.setRowStatus(trueIndex, UPDATE)
 		d.originData[trueIndex] = oldValues
 	}
 	if pkChanged {
@@ -226,7 +426,7 @@ func (d *DataTable) Search(data ...interface{}) []map[string]interface{} {
 	var result []map[string]interface{}
 	for ; i < d.RowCount(); i++ {
 		r := d.Row(i)
-		if r != nil && cmpValue(keyValues, d.getPkValues(d.getSequenceValues(r))[:len(keyValues)]) == 0 {
+		if r != nil && cmpValue(keyValues, d.KeyValues(i)[:len(keyValues)]) == 0 {
 			result = append(result, r)
 		} else {
 			break
@@ -242,7 +442,7 @@ func (d *DataTable) Find(data ...interface{}) int {
 	keyValues := data
 	i := d.search(keyValues...)
 	if i < d.RowCount() &&
-		cmpValue(keyValues, d.getPkValues(d.currentRows.GetRow(d.primaryIndexes.trueIndex(i)))) == 0 {
+		cmpValue(keyValues, d.KeyValues(i)) == 0 {
 		return i
 	} else {
 		return -1
@@ -279,41 +479,9 @@ func stringInSlice(a string, list []string) bool {
 //AsCsv Exports as CSV, if no columns provided it will use all columns
 func (d *DataTable) AsCsv(filterCols ...string) string {
 	bys := &bytes.Buffer{}
-	csvWriter := csv.NewWriter(bys)
-	var outCols []string
-	var outColIndex []int //the column index
-	if len(filterCols) > 0 {
-		outCols = filterCols
-		outColIndex = make([]int, len(filterCols))
-		for i, v := range outCols {
-			colIdx := d.ColumnIndex(v)
-			outColIndex[i] = colIdx
-		}
-	} else {
-		outCols = make([]string, d.ColumnCount())
-		outColIndex = make([]int, d.ColumnCount())
-		for i, v := range d.Columns {
-			outCols[i] = v.Name
-			outColIndex[i] = i
-		}
-	}
-	line := make([]string, len(outCols))
-	//write the head line
-	for i, v := range outCols {
-		line[i] = v
-	}
-	if err := csvWriter.Write(line); err != nil {
+	if err := d.WriteCsv(bys, &WriteCsvOptions{Columns: filterCols}); err != nil {
 		panic(err)
 	}
-	for rowIdx := 0; rowIdx < d.RowCount(); rowIdx++ {
-		for i, colIdx := range outColIndex {
-			line[i] = d.Columns[colIdx].EncodeString(d.GetValue(rowIdx, colIdx))
-		}
-		if err := csvWriter.Write(line); err != nil {
-			panic(err)
-		}
-	}
-	csvWriter.Flush()
 	return bys.String()
 }
 func (d *DataTable) AsJSONP(callback string, columns ...string) string {
@@ -409,13 +577,24 @@ func (d *DataTable) Rows() []map[string]interface{} {
 	}
 	return rev
 }
+// UpdateRow sets the values of the row by the map, the missing columns
+// keep the current values.
 func (d *DataTable) UpdateRow(rowIndex int, r map[string]interface{}) error {
-	return d.SetValues(rowIndex, d.getSequenceValues(r)...)
+	if rowIndex < 0 || rowIndex >= d.RowCount() {
+		return RowNotFoundError
+	}
+	return d.SetValues(rowIndex, d.getSequenceValues(r, d.GetValues(rowIndex))...)
 }
+// NewRow returns a map of the columns for AddRow, the column value is the
+// DefaultValue or the decoded ZeroValue, nil for the auto increment.
 func (d *DataTable) NewRow() map[string]interface{} {
 	result := map[string]interface{}{}
 	for _, col := range d.Columns {
-		result[col.Name] = col.ZeroValue()
+		if col.AutoIncrement {
+			result[col.Name] = nil
+		} else {
+			result[col.Name] = col.defaultValue()
+		}
 	}
 	return result
 }
@@ -452,12 +631,15 @@ func (d *DataTable) getChangeDelete() []*ChangeRow {
 	}
 	return result
 }
+// GetChange returns the changed rows, the values of the computed columns
+// are the zero or null values, see GetChangeWithComputed.
 func (d *DataTable) GetChange() *TableChange {
-	result := &TableChange{}
-	result.DeleteRows = d.getChangeDelete()
-	result.UpdateRows = d.getChangeUpdate()
-	result.InsertRows = d.getChangeInsert()
-	result.RowCount = len(result.DeleteRows) + len(result.UpdateRows) + len(result.InsertRows)
+	result := d.GetChangeWithComputed()
+	if d.hasComputed() {
+		d.clearComputed(result.DeleteRows)
+		d.clearComputed(result.UpdateRows)
+		d.clearComputed(result.InsertRows)
+	}
 	return result
 }
 
@@ -465,11 +647,42 @@ func (d *DataTable) DeleteRow(rowIndex int) error {
 	if rowIndex < 0 || rowIndex >= d.currentRows.Count() {
 		return RowNotFoundError
 	}
+	var e *RowChangeEvent
+	if d.events.hasDeleteHandlers() {
+		e = &RowChangeEvent{Table: d, Action: RowDelete, RowIndex: rowIndex, KeyValues: d.KeyValues(rowIndex),
+			OldValues: d.GetValues(rowIndex)}
+		if err := d.events.fireChanging(d.events.rowDeleting, e); err != nil {
+			return err
+		}
+	}
+	if d.journaling() {
+		trueIndex := d.primaryIndexes.trueIndex(rowIndex)
+		entry := &historyEntry{op: historyDelete, trueIndex: trueIndex, before: d.rowStateOf(trueIndex)}
+		entry.deleted = d.removeRow(rowIndex, true)
+		if err := d.cascade(entry, rowIndex, d.getPkStoreValues(entry.before.values), nil); err != nil {
+			return err
+		}
+		d.recordHistory(entry)
+	} else {
+		d.removeRow(rowIndex, true)
+	}
+	if e != nil {
+		d.events.fireChanged(d.events.rowDeleted, e)
+	}
+	return nil
+}
+
+// removeRow removes the row, the origin values of the unchanged or
+// updated row are added to the deleted rows if keepDeleted is true.
+// It returns whether the deleted row is added.
+func (d *DataTable) removeRow(rowIndex int, keepDeleted bool) bool {
 	trueIndex := d.primaryIndexes.trueIndex(rowIndex)
+	status := d.rowStatus[trueIndex]
 	var oldValues []interface{}
 
-	switch d.rowStatus[trueIndex] {
-	case UNCHANGE, INSERT:
+	//the inserted row is not in the origin data,so it is just removed
+	switch status {
+	case UNCHANGE:
 		oldValues = d.currentRows.GetRow(trueIndex)
 	case UPDATE:
 		oldValues = d.originData[trueIndex]
@@ -477,17 +690,26 @@ func (d *DataTable) DeleteRow(rowIndex int) error {
 		oldValues = nil
 	}
 	lastIdx := d.RowCount() - 1
-	d.changed = true
+	for _, x := range d.indexes {
+		x.remove(trueIndex)
+		if trueIndex != lastIdx {
+			x.rename(lastIdx, trueIndex)
+		}
+	}
+	d.version++
 	d.currentRows.Remove(trueIndex)
-	if oldValues != nil {
+	if oldValues != nil && keepDeleted {
 
 		d.deleteRows.AddRow(oldValues)
 	}
-	d.rowStatus[rowIndex], d.rowStatus = d.rowStatus[lastIdx], d.rowStatus[:lastIdx]
-	d.originData[rowIndex], d.originData = d.originData[lastIdx], d.originData[:lastIdx]
+	d.rowStatus[trueIndex], d.rowStatus = d.rowStatus[lastIdx], d.rowStatus[:lastIdx]
+	d.originData[trueIndex], d.originData = d.originData[lastIdx], d.originData[:lastIdx]
 	d.primaryIndexes.removeIndex(rowIndex, lastIdx)
-
-	return nil
+	if status != UNCHANGE {
+		d.changedRows--
+	}
+	d.updateChanged()
+	return oldValues != nil && keepDeleted
 }
 func (d *DataTable) validValues(vs []interface{}) ([]interface{}, error) {
 	rev := make([]interface{}, len(vs))
@@ -499,7 +721,44 @@ func (d *DataTable) validValues(vs []interface{}) ([]interface{}, error) {
 	}
 	return rev, nil
 }
+// AddValues adds a row of the values, the nil values are filled by the
+// auto increment or the DefaultValue of the columns. The values passed
+// for the computed columns are ignored, they are calculated.
 func (d *DataTable) AddValues(vs ...interface{}) error {
+	vs, err := d.computeValues(d.fillDefaults(vs))
+	if err != nil {
+		return err
+	}
+	return d.addRowValues(vs)
+}
+
+// addRowValues adds the row of the values as is, the events are fired and
+// the constraints are checked as AddValues.
+func (d *DataTable) addRowValues(vs []interface{}) error {
+	var e *RowChangeEvent
+	if d.events.hasRowHandlers() {
+		e = &RowChangeEvent{Table: d, Action: RowAdd, RowIndex: -1, NewValues: vs}
+		if err := d.events.fireChanging(d.events.rowChanging, e); err != nil {
+			return err
+		}
+	}
+	if err := d.checkConstraints(vs, -1); err != nil {
+		return err
+	}
+	if err := d.addValues(vs); err != nil {
+		return err
+	}
+	trueIndex := d.currentRows.Count() - 1
+	d.recordHistory(&historyEntry{op: historyAdd, trueIndex: trueIndex})
+	if e != nil {
+		e.RowIndex = d.rowIndexOf(trueIndex)
+		e.KeyValues = d.KeyValues(e.RowIndex)
+		e.NewValues = d.GetValues(e.RowIndex)
+		d.events.fireChanged(d.events.rowChanged, e)
+	}
+	return nil
+}
+func (d *DataTable) addValues(vs []interface{}) error {
 	if len(vs) != d.ColumnCount() {
 		return NumberOfValueError(len(vs), d.ColumnCount())
 	}
@@ -507,23 +766,67 @@ func (d *DataTable) AddValues(vs ...interface{}) error {
 	if err != nil {
 		return err
 	}
-	keyvalues := d.getPkValues(data)
+	keyvalues := d.getPkStoreValues(data)
 	newKeyIndex := d.primaryIndexes.Search(keyvalues)
 	if len(d.PK) > 0 && newKeyIndex < d.primaryIndexes.Len() &&
 		reflect.DeepEqual(d.KeyValues(newKeyIndex), keyvalues) {
 		return KeyValueExists
 	}
+	if err := d.checkIndexes(data, -1); err != nil {
+		return err
+	}
 	d.changed = true
+	d.version++
 	d.currentRows.AddRow(data)
 	newIndex := d.currentRows.Count() - 1
 	d.rowStatus = append(d.rowStatus, INSERT)
+	d.changedRows++
 	d.originData = append(d.originData, nil)
 	d.primaryIndexes.appendIndex(newKeyIndex, newIndex)
+	for _, x := range d.indexes {
+		x.insert(newIndex)
+	}
+	for i, col := range d.Columns {
+		if col.AutoIncrement {
+			if v, ok := col.Decode(data[i]).(int64); ok {
+				col.seeAutoIncrement(v)
+			}
+		}
+	}
 	return nil
 
 }
+
+// fillDefaults returns the values whose nil are filled by the auto
+// increment or the DefaultValue of the columns.
+func (d *DataTable) fillDefaults(vs []interface{}) []interface{} {
+	if len(vs) != d.ColumnCount() {
+		return vs
+	}
+	var result []interface{}
+	for i, col := range d.Columns {
+		if vs[i] != nil || (!col.AutoIncrement && col.DefaultValue == nil) {
+			continue
+		}
+		if result == nil {
+			result = append([]interface{}{}, vs...)
+		}
+		if col.AutoIncrement {
+			result[i] = col.nextAutoIncrement()
+		} else {
+			result[i] = col.DefaultValue
+		}
+	}
+	if result == nil {
+		return vs
+	}
+	return result
+}
+
+// AddRow adds a row of the map, the missing columns are filled as the nil
+// values by AddValues.
 func (d *DataTable) AddRow(r map[string]interface{}) error {
-	return d.AddValues(d.getSequenceValues(r)...)
+	return d.AddValues(d.getSequenceValues(r, nil)...)
 }
 func (p *pkIndex) removeIndex(rowIndex, lastTrueIdx int) {
 	//Delete preserving order
@@ -602,12 +905,23 @@ func (d *DataTable) Clear() {
 	d.deleteRows = &dataRows{}
 	d.primaryIndexes = pkIndex{dataTable: d}
 	d.rowStatus = nil
+	d.changedRows = 0
 	d.originData = nil
 	for _, c := range d.Columns {
 		d.currentRows.AddColumn(c.StoreType())
 		d.deleteRows.AddColumn(c.StoreType())
 	}
+	for _, x := range d.indexes {
+		x.index = nil
+	}
 	d.changed = false
+	d.version++
+	d.history.clear()
+	for _, id := range d.events.order {
+		if h, ok := d.events.tableCleared[id]; ok {
+			h(d)
+		}
+	}
 }
 func (p *pkIndex) Less(i, j int) bool {
 	return cmpValue(p.dataTable.KeyValues(i), p.dataTable.KeyValues(j)) < 0
@@ -634,28 +948,146 @@ func (d *DataTable) SetPK(names ...string) {
 	}
 	d.PK = names
 	d.primaryIndexes.rebuildPKIndex()
+	d.version++
+	d.history.clear()
 }
 func (d *DataTable) HasChange() bool {
 	return d.changed
 }
+// Merge merges srcTable into the table, same as
+// MergeWith(srcTable, false, MissingSchemaAdd).
 func (d *DataTable) Merge(srcTable *DataTable) error {
-	if d.ColumnCount() != srcTable.ColumnCount() {
-		return fmt.Errorf("the src table columncount:%d not is %d", srcTable.ColumnCount(), d.ColumnCount())
+	return d.MergeWith(srcTable, false, MissingSchemaAdd)
+}
+
+// MergeWith merges the rows of srcTable into the table by primary key.
+// A source row whose key already exists updates the target row in place
+// (UPDATE status, the origin data is kept), a new key is added as INSERT
+// and a row deleted in the source deletes the matching target row.
+// When preserveChanges is true, target rows with pending changes are left
+// untouched. Source columns missing in the table are handled by action.
+// A table without primary key only appends the source rows.
+func (d *DataTable) MergeWith(srcTable *DataTable, preserveChanges bool, action MissingSchemaAction) error {
+	colMap, err := d.mergeSchema(srcTable, action)
+	if err != nil {
+		return err
 	}
-	for i, col := range d.Columns {
-		if srcTable.Columns[i].DataType != col.DataType {
-			return fmt.Errorf("the column:%s data type %s not equal %s", col.Name, col.DataType, srcTable.Columns[i].DataType)
+	srcPK := make([]int, len(d.PK))
+	for i, c := range d.PK {
+		if srcPK[i] = srcTable.ColumnIndex(c); srcPK[i] == -1 {
+			return fmt.Errorf("the src table not have the primary key column:%s", c)
 		}
 	}
-	pks := make([]int, len(srcTable.primaryIndexes.index))
-	copy(pks, srcTable.primaryIndexes.index)
-	for i, _ := range pks {
-		pks[i] += d.RowCount()
+	for rowIdx := 0; rowIdx < srcTable.RowCount(); rowIdx++ {
+		srcValues := srcTable.GetValues(rowIdx)
+		foundIdx := -1
+		if len(d.PK) > 0 {
+			foundIdx = d.Find(pickValues(srcValues, srcPK)...)
+		}
+		if foundIdx == -1 {
+			if err := d.AddValues(d.mergeValues(d.missingValues(), srcValues, colMap)...); err != nil {
+				return err
+			}
+			continue
+		}
+		if preserveChanges && d.rowStatus[d.primaryIndexes.trueIndex(foundIdx)] != UNCHANGE {
+			continue
+		}
+		if err := d.SetValues(foundIdx, d.mergeValues(d.GetValues(foundIdx), srcValues, colMap)...); err != nil {
+			return err
+		}
+	}
+	if len(d.PK) == 0 {
+		return nil
+	}
+	for i := 0; i < srcTable.deleteRows.Count(); i++ {
+		srcValues := srcTable.deleteRows.GetRow(i)
+		for j, v := range srcValues {
+			srcValues[j] = srcTable.Columns[j].Decode(v)
+		}
+		foundIdx := d.Find(pickValues(srcValues, srcPK)...)
+		if foundIdx == -1 ||
+			preserveChanges && d.rowStatus[d.primaryIndexes.trueIndex(foundIdx)] != UNCHANGE {
+			continue
+		}
+		if err := d.DeleteRow(foundIdx); err != nil {
+			return err
+		}
 	}
-	d.primaryIndexes.index = append(d.primaryIndexes.index, pks...)
-	d.currentRows.Merge(srcTable.currentRows)
-	d.deleteRows.Merge(srcTable.deleteRows)
-	d.rowStatus = append(d.rowStatus, srcTable.rowStatus...)
-	d.originData = append(d.originData, srcTable.originData...)
 	return nil
 }
+
+// mergeSchema checks the source columns against the table, adds the missing
+// columns if action is MissingSchemaAdd, and returns for every source column
+// the index of the table column it maps to (-1 is ignored).
+func (d *DataTable) mergeSchema(srcTable *DataTable, action MissingSchemaAction) ([]int, error) {
+	var missing []*DataColumn
+	for _, col := range srcTable.Columns {
+		if i := d.ColumnIndex(col.Name); i == -1 {
+			missing = append(missing, col)
+		} else if d.Columns[i].DataType != col.DataType {
+			return nil, fmt.Errorf("the column:%s data type %s not equal %s", col.Name, d.Columns[i].DataType, col.DataType)
+		}
+	}
+	if len(missing) > 0 {
+		switch action {
+		case MissingSchemaError:
+			return nil, ColumnNotFoundError(missing[0].Name)
+		case MissingSchemaAdd:
+			for _, col := range missing {
+				d.AddColumn(col.Clone())
+			}
+		}
+	}
+	colMap := make([]int, srcTable.ColumnCount())
+	for i, col := range srcTable.Columns {
+		colMap[i] = d.ColumnIndex(col.Name)
+	}
+	return colMap, nil
+}
+func (d *DataTable) mergeValues(dest, src []interface{}, colMap []int) []interface{} {
+	for i, v := range src {
+		if colMap[i] > -1 {
+			dest[colMap[i]] = v
+		}
+	}
+	return dest
+}
+
+// missingValues returns the values of the columns missing in a source
+// row, nil for the column with the auto increment or DefaultValue so
+// AddValues fills it, else the decoded zero value(nil for nullable column).
+func (d *DataTable) missingValues() []interface{} {
+	result := make([]interface{}, d.ColumnCount())
+	for i, col := range d.Columns {
+		if !col.AutoIncrement && col.DefaultValue == nil {
+			result[i] = col.Decode(col.ZeroValue())
+		}
+	}
+	return result
+}
+
+// decodeValues returns the decoded values of a stored row.
+func (d *DataTable) decodeValues(values []interface{}) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = d.Columns[i].Decode(v)
+	}
+	return result
+}
+
+// encodeValues returns the stored values of the decoded values.
+func (d *DataTable) encodeValues(values []interface{}) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = d.Columns[i].Encode(v)
+	}
+	return result
+}
+func pickValues(values []interface{}, indexes []int) []interface{} {
+	result := make([]interface{}, len(indexes))
+	for i, v := range indexes {
+		result[i] = values[v]
+	}
+	return result
+}
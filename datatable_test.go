@@ -1,6 +1,8 @@
 package datatable
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"reflect"
@@ -62,6 +64,32 @@ func TestDataTable_NoPrimaryKey(t *testing.T) {
 		t.Error(err)
 	}
 }
+func TestDataTable_NullablePrimaryKey(t *testing.T) {
+	table := NewDataTable("table1")
+	table.AddColumn(StringColumn("code", 0, false))
+	table.AddColumn(Int64Column("no", false))
+	table.AddColumn(NewStringColumn("name"))
+	table.SetPK("code", "no")
+	table.AddValues("b", int64(1), "b1")
+	table.AddValues("a", int64(2), "a2")
+	table.AddValues("a", int64(1), "a1")
+	//the key values are decoded, not the stored pointers
+	if i := table.Find("a", int64(1)); i != 0 || !reflect.DeepEqual(table.KeyValues(i), []interface{}{"a", int64(1)}) {
+		t.Error(i, table.KeyValues(i))
+	}
+	if err := table.AddValues("a", int64(2), "dup"); err != KeyValueExists {
+		t.Error(err)
+	}
+	if err := table.SetValues(table.Find("b", int64(1)), "a", int64(2), "dup"); err != KeyValueExists {
+		t.Error(err)
+	}
+	if err := table.SetValues(table.Find("b", int64(1)), "c", int64(1), "c1"); err != nil {
+		t.Fatal(err)
+	}
+	if table.Find("b", int64(1)) != -1 || table.Find("c", int64(1)) != 2 || table.GetValue(2, 2) != "c1" {
+		t.Error(table.AsCsv())
+	}
+}
 func TestMemory(t *testing.T) {
 	m := runtime.MemStats{}
 	runtime.ReadMemStats(&m)
@@ -399,6 +427,16 @@ func TestHasChange(t *testing.T) {
 	if table.HasChange() == true {
 		t.Error("error")
 	}
+	//removing the inserted row leaves nothing changed
+	table.AddValues("new", int64(1))
+	table.DeleteRow(1)
+	if table.HasChange() == true {
+		t.Error("error")
+	}
+	table.DeleteRow(0)
+	if table.HasChange() == false {
+		t.Error("error")
+	}
 
 }
 
@@ -508,3 +546,212 @@ func TestInterfaceColumn(t *testing.T) {
 		t.Error(err)
 	}
 }
+func TestMergeWith(t *testing.T) {
+	table := NewDataTable("table1")
+	table.AddColumn(NewStringColumn("column1"))
+	table.AddColumn(NewStringColumn("column2"))
+	table.SetPK("column1")
+	table.AddValues("row1", "row1_1")
+	table.AddValues("row2", "row2_1")
+	table.AddValues("row3", "row3_1")
+	table.AcceptChange()
+	table.SetValues(table.Find("row2"), "row2", "local")
+
+	src := NewDataTable("table1")
+	src.AddColumn(NewStringColumn("column1"))
+	src.AddColumn(NewStringColumn("column2"))
+	src.AddColumn(NewInt64Column("column3"))
+	src.SetPK("column1")
+	src.AddValues("row1", "row1_2", int64(1))
+	src.AddValues("row2", "row2_2", int64(2))
+	src.AddValues("row3", "row3_1", int64(3))
+	src.AddValues("row4", "row4_1", int64(4))
+	src.AcceptChange()
+	src.DeleteRow(src.Find("row3"))
+
+	if err := table.MergeWith(src, true, MissingSchemaError); err == nil {
+		t.Error("the missing column must be error")
+	}
+	if table.ColumnCount() != 2 {
+		t.Error("error")
+	}
+	if err := table.MergeWith(src, true, MissingSchemaIgnore); err != nil {
+		t.Fatal(err)
+	}
+	if table.RowCount() != 3 || table.ColumnCount() != 2 {
+		t.Error("error", table.RowCount())
+	}
+	if v := table.Row(table.Find("row1"))["column2"]; v != "row1_2" {
+		t.Error("error", v)
+	}
+	if v := table.Row(table.Find("row2"))["column2"]; v != "local" {
+		t.Error("error", v)
+	}
+	if table.Find("row3") != -1 || table.Find("row4") == -1 {
+		t.Error("error")
+	}
+	chg := table.GetChange()
+	if len(chg.InsertRows) != 1 || len(chg.UpdateRows) != 2 || len(chg.DeleteRows) != 1 {
+		t.Error(fmt.Sprintf("error,count:%#v", chg))
+	}
+	if r := table.GetOriginRow(table.Find("row1")); r["column2"] != "row1_1" {
+		t.Error("error", r)
+	}
+
+	if err := table.Merge(src); err != nil {
+		t.Fatal(err)
+	}
+	if table.ColumnCount() != 3 || table.RowCount() != 3 {
+		t.Error("error")
+	}
+	if r := table.Row(table.Find("row2")); r["column2"] != "row2_2" || r["column3"] != int64(2) {
+		t.Error("error", r)
+	}
+}
+func TestMergeWithMissingColumns(t *testing.T) {
+	table := NewDataTable("items")
+	id := NewInt64Column("id")
+	id.AutoIncrement = true
+	id.AutoIncrementSeed = 1
+	table.AddColumn(id)
+	table.AddColumn(NewStringColumn("name"))
+	status := NewStringColumn("status")
+	status.DefaultValue = "new"
+	table.AddColumn(status)
+	table.AddColumn(NewInt64Column("qty"))
+	table.SetPK("id")
+	src := NewDataTable("items")
+	src.AddColumn(NewInt64Column("id"))
+	src.AddColumn(NewStringColumn("name"))
+	src.SetPK("id")
+	src.AddValues(int64(5), "a")
+	//the missing columns are filled by the default, the key is merged
+	if err := table.MergeWith(src, false, MissingSchemaError); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.AddValues(nil, "b", nil, int64(1)); err != nil {
+		t.Fatal(err)
+	}
+	if table.AsCsv() != "id,name,status,qty\n5,a,new,0\n6,b,new,1\n" {
+		t.Error(table.AsCsv())
+	}
+}
+func TestRejectChanges(t *testing.T) {
+	table := CreateTestData()
+	table.CreateIndex("idx", []string{"column3"}, false)
+	table.AcceptChange()
+	want := table.AsCsv()
+	table.SetValues(table.Find("first", int64(10)), "zzz", int64(10), "changed")
+	table.DeleteRow(table.Find("bbb", int64(10)))
+	table.AddValues("new", int64(1), "test")
+	table.DeleteRow(table.Find("new", int64(1)))
+	table.AddValues("bbb", int64(10), "test2")
+	if chg := table.GetChange(); chg.RowCount != 3 || len(chg.DeleteRows) != 1 {
+		t.Error(fmt.Sprintf("error,count:%#v", chg))
+	}
+	table.RejectChanges()
+	if table.HasChange() || table.AsCsv() != want || table.Find("zzz", int64(10)) != -1 {
+		t.Error(table.AsCsv())
+	}
+	if rows := table.SearchBy("idx", "test1"); len(rows) != 4 {
+		t.Error(rows)
+	}
+	//reject a row
+	table.SetValues(table.Find("first", int64(10)), "zzz", int64(10), "changed")
+	table.AddValues("new", int64(1), "test")
+	if err := table.RejectRowChanges(table.Find("new", int64(1))); err != nil || table.RowCount() != 5 {
+		t.Error(err)
+	}
+	if !table.HasChange() {
+		t.Error("error")
+	}
+	if err := table.RejectRowChanges(table.Find("zzz", int64(10))); err != nil {
+		t.Error(err)
+	}
+	if table.HasChange() || table.AsCsv() != want {
+		t.Error(table.AsCsv())
+	}
+	if err := table.RejectRowChanges(10); err != RowNotFoundError {
+		t.Error(err)
+	}
+}
+
+func TestDefaultValues(t *testing.T) {
+	table := NewDataTable("t")
+	id := table.AddColumn(NewInt64Column("id"))
+	id.AutoIncrement = true
+	id.AutoIncrementSeed = -1
+	id.AutoIncrementStep = -1
+	status := table.AddColumn(NewStringColumn("status"))
+	status.DefaultValue = "new"
+	table.AddColumn(StringColumn("memo", 0, false))
+	table.SetPK("id")
+	if err := table.AddRow(map[string]interface{}{"memo": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.AddValues(nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.AddRow(table.NewRow()); err != nil {
+		t.Fatal(err)
+	}
+	if table.Find(int64(-1)) == -1 || table.Find(int64(-2)) == -1 || table.Find(int64(-3)) == -1 {
+		t.Error(table.AsCsv())
+	}
+	if r := table.Row(table.Find(int64(-1))); r["status"] != "new" || r["memo"] != "a" {
+		t.Error(r)
+	}
+	if r := table.Row(table.Find(int64(-2))); r["status"] != "new" || r["memo"] != nil {
+		t.Error(r)
+	}
+	//the explicit value moves the next value
+	table.AddValues(int64(-10), "old", nil)
+	table.AddValues(nil, "x", nil)
+	if table.Find(int64(-11)) == -1 {
+		t.Error(table.AsCsv())
+	}
+	//the missing column of UpdateRow keeps the value
+	if err := table.UpdateRow(table.Find(int64(-1)), map[string]interface{}{"id": int64(-1), "memo": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if r := table.Row(table.Find(int64(-1))); r["status"] != "new" || r["memo"] != "b" {
+		t.Error(r)
+	}
+	//the defaults and the auto increment are persisted
+	bys, err := json.Marshal(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := NewDataTable("")
+	if err := json.Unmarshal(bys, read); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if _, err := table.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := NewDataTable("")
+	if _, err := snapshot.ReadFrom(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []*DataTable{read, snapshot} {
+		if r := d.Row(d.Find(int64(-2))); r["memo"] != nil {
+			t.Error(r)
+		}
+		if err := d.AddValues(nil, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if i := d.Find(int64(-12)); i == -1 || d.Row(i)["status"] != "new" {
+			t.Error(d.AsCsv())
+		}
+	}
+	up := NewDataTable("t")
+	c := up.AddColumn(NewInt64Column("id"))
+	c.AutoIncrement = true
+	c.AutoIncrementSeed = 1
+	up.AddValues(int64(5))
+	up.AddValues(nil)
+	if up.GetValue(1, 0) != int64(6) {
+		t.Error(up.AsCsv())
+	}
+}
@@ -0,0 +1,254 @@
+package datatable
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DataViewRowState selects the rows of a DataView by the row status.
+type DataViewRowState int
+
+const (
+	ViewUnchanged DataViewRowState = 1 << iota
+	ViewAdded
+	ViewModified
+	//the deleted rows with the origin values
+	ViewDeleted
+	ViewCurrentRows = ViewUnchanged | ViewAdded | ViewModified
+	ViewAllRows     = ViewCurrentRows | ViewDeleted
+)
+
+// viewRow is a row of the view, index is the table row index or the
+// index of the deleted rows.
+type viewRow struct {
+	deleted bool
+	index   int
+}
+
+// DataView is a filtered and sorted view of a table, like the .Net
+// DataView. It is rebuilt when the table changed, so it is always
+// consistent with the table.
+type DataView struct {
+	table    *DataTable
+	filter   *expression
+	sort     []SortColumn
+	rowState DataViewRowState
+	//the table version of rows
+	version int
+	rows    []viewRow
+	err     error
+}
+
+// NewDataView returns a view of the table, the rowFilter is an expression
+// as DataTable.Select, the sort is a specification as ParseSort.
+func NewDataView(table *DataTable, rowFilter string, sort string, rowState DataViewRowState) (*DataView, error) {
+	v := &DataView{table: table, rowState: rowState}
+	if err := v.SetRowFilter(rowFilter); err != nil {
+		return nil, err
+	}
+	sortColumns, err := ParseSort(sort)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.SetSort(sortColumns...); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Table returns the table of the view.
+func (v *DataView) Table() *DataTable {
+	return v.table
+}
+
+// RowFilter returns the filter expression.
+func (v *DataView) RowFilter() string {
+	if v.filter == nil {
+		return ""
+	}
+	return v.filter.src
+}
+
+// SetRowFilter sets the filter expression, empty for all rows.
+func (v *DataView) SetRowFilter(rowFilter string) error {
+	var filter *expression
+	if strings.TrimSpace(rowFilter) != "" {
+		var err error
+		if filter, err = compileFilter(v.table, rowFilter); err != nil {
+			return err
+		}
+	}
+	v.filter = filter
+	v.rows = nil
+	return nil
+}
+
+// Sort returns the sort columns.
+func (v *DataView) Sort() []SortColumn {
+	return v.sort
+}
+
+// SetSort sets the sort columns, no column keeps the table order.
+func (v *DataView) SetSort(columns ...SortColumn) error {
+	for _, c := range columns {
+		if v.table.ColumnIndex(c.Column) == -1 {
+			return ColumnNotFoundError(c.Column)
+		}
+	}
+	v.sort = columns
+	v.rows = nil
+	return nil
+}
+
+// RowStateFilter returns the row state filter.
+func (v *DataView) RowStateFilter() DataViewRowState {
+	return v.rowState
+}
+
+// SetRowStateFilter sets the row state filter.
+func (v *DataView) SetRowStateFilter(rowState DataViewRowState) {
+	v.rowState = rowState
+	v.rows = nil
+}
+
+// Err returns the error of evaluating the filter at last rebuild, the row
+// with error is excluded from the view.
+func (v *DataView) Err() error {
+	v.refresh()
+	return v.err
+}
+
+// Count returns the count of rows in the view.
+func (v *DataView) Count() int {
+	v.refresh()
+	return len(v.rows)
+}
+
+// Row returns the i-th row of the view, the deleted row returns the
+// origin values.
+func (v *DataView) Row(i int) map[string]interface{} {
+	vals := v.values(i)
+	result := map[string]interface{}{}
+	for j, col := range v.table.Columns {
+		result[col.Name] = vals[j]
+	}
+	return result
+}
+
+// RowIndex returns the table row index of the i-th row of the view, -1
+// for a deleted row.
+func (v *DataView) RowIndex(i int) int {
+	v.refresh()
+	if v.rows[i].deleted {
+		return -1
+	}
+	return v.rows[i].index
+}
+
+// ToTable returns a new table with the columns(all if empty) and the rows
+// of the view in order, without primary key.
+func (v *DataView) ToTable(columns ...string) (*DataTable, error) {
+	v.refresh()
+	if len(columns) == 0 {
+		columns = v.table.ColumnNames()
+	}
+	result := NewDataTable(v.table.TableName)
+	colIdx := make([]int, len(columns))
+	for i, c := range columns {
+		if colIdx[i] = v.table.ColumnIndex(c); colIdx[i] == -1 {
+			return nil, ColumnNotFoundError(c)
+		}
+		if result.ColumnIndex(c) > -1 {
+			return nil, ColumnExistsError
+		}
+		result.AddColumn(v.table.Columns[colIdx[i]].Clone())
+	}
+	for i := range v.rows {
+		if err := result.addValues(pickValues(v.values(i), colIdx)); err != nil {
+			return nil, err
+		}
+	}
+	result.AcceptChange()
+	return result, nil
+}
+
+// values returns the decoded values of the i-th row.
+func (v *DataView) values(i int) []interface{} {
+	v.refresh()
+	r := v.rows[i]
+	if r.deleted {
+		return v.deletedValues(r.index)
+	}
+	return v.table.GetValues(r.index)
+}
+
+func (v *DataView) stateOf(status byte) DataViewRowState {
+	switch status {
+	case INSERT:
+		return ViewAdded
+	case UPDATE:
+		return ViewModified
+	default:
+		return ViewUnchanged
+	}
+}
+
+// refresh rebuilds the rows if the table or the view changed.
+func (v *DataView) refresh() {
+	if v.rows != nil && v.version == v.table.version {
+		return
+	}
+	v.rows = []viewRow{}
+	v.version = v.table.version
+	v.err = nil
+	var keys [][]interface{}
+	add := func(r viewRow, values []interface{}) {
+		if v.filter != nil {
+			ok, err := v.filter.match(values)
+			if err != nil && v.err == nil {
+				v.err = fmt.Errorf("row %d: %v", r.index, err)
+			}
+			if !ok {
+				return
+			}
+		}
+		v.rows = append(v.rows, r)
+		keys = append(keys, values)
+	}
+	d := v.table
+	for i := 0; i < d.RowCount(); i++ {
+		if v.rowState&v.stateOf(d.rowStatus[d.primaryIndexes.trueIndex(i)]) != 0 {
+			add(viewRow{index: i}, d.GetValues(i))
+		}
+	}
+	if v.rowState&ViewDeleted != 0 {
+		for i := 0; i < d.deleteRows.Count(); i++ {
+			add(viewRow{deleted: true, index: i}, v.deletedValues(i))
+		}
+	}
+	if len(v.sort) == 0 {
+		return
+	}
+	s := &sortKeys{columns: v.sort, keys: make([][]interface{}, len(keys))}
+	for i, values := range keys {
+		key := make([]interface{}, len(v.sort))
+		for j, c := range v.sort {
+			key[j] = values[d.ColumnIndex(c.Column)]
+		}
+		s.keys[i] = key
+	}
+	rows := make([]viewRow, len(v.rows))
+	for i, o := range s.order() {
+		rows[i] = v.rows[o]
+	}
+	v.rows = rows
+}
+
+// deletedValues returns the decoded values of the deleted row.
+func (v *DataView) deletedValues(index int) []interface{} {
+	result := v.table.deleteRows.GetRow(index)
+	for j, val := range result {
+		result[j] = v.table.Columns[j].Decode(val)
+	}
+	return result
+}
@@ -0,0 +1,109 @@
+package datatable
+
+import (
+	"reflect"
+	"testing"
+)
+
+func viewColumn(v *DataView, column string) []interface{} {
+	result := []interface{}{}
+	for i := 0; i < v.Count(); i++ {
+		result = append(result, v.Row(i)[column])
+	}
+	return result
+}
+func TestDataView(t *testing.T) {
+	table := createSelectData()
+	table.AcceptChange()
+	view, err := NewDataView(table, "column2 > 1", "x DESC NULLS LAST", ViewCurrentRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := viewColumn(view, "column1"); !reflect.DeepEqual(got, []interface{}{"row3", "other", "row2"}) {
+		t.Error(got)
+	}
+	//the view follows the table
+	table.AddValues("row4", int64(7), 9.5, nil)
+	table.DeleteRow(table.Find("row3"))
+	table.SetValues(table.Find("row1"), "row1", int64(2), 0.5, nil)
+	if got := viewColumn(view, "column1"); !reflect.DeepEqual(got, []interface{}{"row4", "row1", "other", "row2"}) {
+		t.Error(got)
+	}
+	if i := view.RowIndex(1); table.GetValue(i, 0) != "row1" {
+		t.Error("error", i)
+	}
+	view.SetRowStateFilter(ViewAdded | ViewDeleted)
+	if got := viewColumn(view, "column1"); !reflect.DeepEqual(got, []interface{}{"row4", "row3"}) {
+		t.Error(got)
+	}
+	if view.RowIndex(1) != -1 {
+		t.Error("error")
+	}
+	view.SetRowStateFilter(ViewModified)
+	if view.Count() != 1 || view.Row(0)["column1"] != "row1" {
+		t.Error("error")
+	}
+	view.SetRowStateFilter(ViewCurrentRows)
+	if err := view.SetRowFilter(""); err != nil {
+		t.Error(err)
+	}
+	view.SetSort(SortColumn{Column: "column2", Desc: true})
+	result, err := view.ToTable("column2", "column1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.GetColumnValues(0), []interface{}{int64(9), int64(7), int64(6), int64(2)}) ||
+		result.ColumnCount() != 2 || result.HasChange() {
+		t.Error(result.AsTabText())
+	}
+	if err := view.SetRowFilter("column1 > 1"); err == nil {
+		t.Error("must be error")
+	}
+	if _, err := NewDataView(table, "", "nothing", ViewCurrentRows); err == nil {
+		t.Error("must be error")
+	}
+}
+
+func TestDataViewRowStateRefresh(t *testing.T) {
+	table := CreateTestData()
+	table.AcceptChange()
+	view, err := NewDataView(table, "", "", ViewModified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table.SetModified(0)
+	if view.Count() != 1 {
+		t.Error(view.Count())
+	}
+	//the status changed without the values is seen by the view
+	if err := table.RejectRowChanges(0); err != nil || view.Count() != 0 {
+		t.Error(err, view.Count())
+	}
+	table.DeleteRow(0)
+	view.SetRowStateFilter(ViewUnchanged)
+	n := view.Count()
+	if _, err := table.RestoreDeletedRow(0); err != nil || view.Count() != n+1 {
+		t.Error(err, view.Count())
+	}
+}
+
+func TestDataViewToTableKeepsNulls(t *testing.T) {
+	table := NewDataTable("items")
+	table.AddColumn(NewInt64Column("id"))
+	table.AddColumn(StringColumn("code", 0, false))
+	table.SetPK("id")
+	table.AddValues(int64(1), nil)
+	table.AddValues(int64(2), "b")
+	table.Columns[1].DefaultValue = "a"
+	view, err := NewDataView(table, "", "id", ViewCurrentRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := view.ToTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RowCount() != 2 || result.GetValue(0, 1) != nil || result.GetValue(1, 1) != "b" {
+		t.Error(result.AsCsv())
+	}
+}
@@ -0,0 +1,216 @@
+package datatable
+
+import "reflect"
+
+// RowAction is the action of a row change event.
+type RowAction int
+
+const (
+	RowAdd RowAction = iota
+	RowChange
+	RowDelete
+	//the change of the row is accepted by AcceptChange
+	RowCommit
+)
+
+// RowChangeEvent describes a change of a row. RowIndex is -1 for the row
+// being added, KeyValues are the primary key values of the row, the
+// values are decoded, OldValues is nil for the added row and NewValues is
+// nil for the deleted row.
+type RowChangeEvent struct {
+	Table     *DataTable
+	Action    RowAction
+	RowIndex  int
+	KeyValues []interface{}
+	OldValues []interface{}
+	NewValues []interface{}
+}
+
+// ColumnChangeEvent describes a change of a column value in a row.
+type ColumnChangeEvent struct {
+	Table    *DataTable
+	RowIndex int
+	Column   string
+	OldValue interface{}
+	NewValue interface{}
+}
+
+// RowChangingHandler is called before the row change, a returned error
+// vetoes the change and is returned by the operation.
+type RowChangingHandler func(e *RowChangeEvent) error
+
+// RowChangedHandler is called after the row changed.
+type RowChangedHandler func(e *RowChangeEvent)
+
+// ColumnChangedHandler is called after the column value changed.
+type ColumnChangedHandler func(e *ColumnChangeEvent)
+
+// TableClearedHandler is called after the table cleared.
+type TableClearedHandler func(table *DataTable)
+
+// tableEvents holds the handlers of the table, every handler has an id to
+// remove it.
+type tableEvents struct {
+	nextID        int
+	rowChanging   map[int]RowChangingHandler
+	rowChanged    map[int]RowChangedHandler
+	rowDeleting   map[int]RowChangingHandler
+	rowDeleted    map[int]RowChangedHandler
+	columnChanged map[int]ColumnChangedHandler
+	tableCleared  map[int]TableClearedHandler
+	//the ids in order of subscription
+	order []int
+}
+
+// subscribe returns the id of the new handler and the function removing
+// it.
+func (ev *tableEvents) subscribe(remove func(id int)) (int, func()) {
+	ev.nextID++
+	id := ev.nextID
+	ev.order = append(ev.order, id)
+	return id, func() {
+		remove(id)
+		for i, v := range ev.order {
+			if v == id {
+				ev.order = append(ev.order[:i], ev.order[i+1:]...)
+				break
+			}
+		}
+	}
+}
+func (ev *tableEvents) hasRowHandlers() bool {
+	return len(ev.rowChanging)+len(ev.rowChanged)+len(ev.columnChanged) > 0
+}
+func (ev *tableEvents) hasDeleteHandlers() bool {
+	return len(ev.rowDeleting)+len(ev.rowDeleted) > 0
+}
+func (ev *tableEvents) fireChanging(handlers map[int]RowChangingHandler, e *RowChangeEvent) error {
+	for _, id := range ev.order {
+		if h, ok := handlers[id]; ok {
+			if err := h(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+func (ev *tableEvents) fireChanged(handlers map[int]RowChangedHandler, e *RowChangeEvent) {
+	for _, id := range ev.order {
+		if h, ok := handlers[id]; ok {
+			h(e)
+		}
+	}
+}
+
+// fireColumnsChanged calls the column changed handlers for every column
+// whose value changed.
+func (ev *tableEvents) fireColumnsChanged(e *RowChangeEvent) {
+	if len(ev.columnChanged) == 0 {
+		return
+	}
+	for i, col := range e.Table.Columns {
+		if reflect.DeepEqual(e.OldValues[i], e.NewValues[i]) {
+			continue
+		}
+		ce := &ColumnChangeEvent{Table: e.Table, RowIndex: e.RowIndex, Column: col.Name, OldValue: e.OldValues[i], NewValue: e.NewValues[i]}
+		for _, id := range ev.order {
+			if h, ok := ev.columnChanged[id]; ok {
+				h(ce)
+			}
+		}
+	}
+}
+
+// OnRowChanging adds the handler called before a row is added or changed
+// by AddValues, SetValues and Merge, returns the function removing it.
+func (d *DataTable) OnRowChanging(h RowChangingHandler) func() {
+	ev := &d.events
+	id, remove := ev.subscribe(func(id int) { delete(ev.rowChanging, id) })
+	if ev.rowChanging == nil {
+		ev.rowChanging = map[int]RowChangingHandler{}
+	}
+	ev.rowChanging[id] = h
+	return remove
+}
+
+// OnRowChanged adds the handler called after a row is added, changed or
+// its change is accepted by AcceptChange, returns the function removing
+// it.
+func (d *DataTable) OnRowChanged(h RowChangedHandler) func() {
+	ev := &d.events
+	id, remove := ev.subscribe(func(id int) { delete(ev.rowChanged, id) })
+	if ev.rowChanged == nil {
+		ev.rowChanged = map[int]RowChangedHandler{}
+	}
+	ev.rowChanged[id] = h
+	return remove
+}
+
+// OnRowDeleting adds the handler called before a row is deleted by
+// DeleteRow, returns the function removing it.
+func (d *DataTable) OnRowDeleting(h RowChangingHandler) func() {
+	ev := &d.events
+	id, remove := ev.subscribe(func(id int) { delete(ev.rowDeleting, id) })
+	if ev.rowDeleting == nil {
+		ev.rowDeleting = map[int]RowChangingHandler{}
+	}
+	ev.rowDeleting[id] = h
+	return remove
+}
+
+// OnRowDeleted adds the handler called after a row is deleted, returns
+// the function removing it.
+func (d *DataTable) OnRowDeleted(h RowChangedHandler) func() {
+	ev := &d.events
+	id, remove := ev.subscribe(func(id int) { delete(ev.rowDeleted, id) })
+	if ev.rowDeleted == nil {
+		ev.rowDeleted = map[int]RowChangedHandler{}
+	}
+	ev.rowDeleted[id] = h
+	return remove
+}
+
+// OnColumnChanged adds the handler called after a column value of a row
+// is changed by SetValues, returns the function removing it.
+func (d *DataTable) OnColumnChanged(h ColumnChangedHandler) func() {
+	ev := &d.events
+	id, remove := ev.subscribe(func(id int) { delete(ev.columnChanged, id) })
+	if ev.columnChanged == nil {
+		ev.columnChanged = map[int]ColumnChangedHandler{}
+	}
+	ev.columnChanged[id] = h
+	return remove
+}
+
+// OnTableCleared adds the handler called after the table is cleared by
+// Clear, returns the function removing it.
+func (d *DataTable) OnTableCleared(h TableClearedHandler) func() {
+	ev := &d.events
+	id, remove := ev.subscribe(func(id int) { delete(ev.tableCleared, id) })
+	if ev.tableCleared == nil {
+		ev.tableCleared = map[int]TableClearedHandler{}
+	}
+	ev.tableCleared[id] = h
+	return remove
+}
+
+// commitEvents returns the events of the rows with changes for
+// AcceptChange.
+func (d *DataTable) commitEvents() []*RowChangeEvent {
+	if len(d.events.rowChanged) == 0 {
+		return nil
+	}
+	var result []*RowChangeEvent
+	for i := 0; i < d.RowCount(); i++ {
+		trueIndex := d.primaryIndexes.trueIndex(i)
+		if d.rowStatus[trueIndex] == UNCHANGE {
+			continue
+		}
+		e := &RowChangeEvent{Table: d, Action: RowCommit, RowIndex: i, KeyValues: d.KeyValues(i), NewValues: d.GetValues(i)}
+		if d.rowStatus[trueIndex] == UPDATE {
+			e.OldValues = d.decodeValues(d.originData[trueIndex])
+		}
+		result = append(result, e)
+	}
+	return result
+}
@@ -0,0 +1,86 @@
+package datatable
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+	table := CreateTestData()
+	table.AcceptChange()
+	var log []string
+	vetoErr := errors.New("veto")
+	removeChanging := table.OnRowChanging(func(e *RowChangeEvent) error {
+		if e.NewValues[2] == "veto" {
+			return vetoErr
+		}
+		log = append(log, fmt.Sprint("changing ", e.Action, e.RowIndex, e.KeyValues, e.OldValues, e.NewValues))
+		return nil
+	})
+	table.OnRowChanged(func(e *RowChangeEvent) {
+		log = append(log, fmt.Sprint("changed ", e.Action, e.RowIndex, e.KeyValues, e.OldValues, e.NewValues))
+	})
+	table.OnColumnChanged(func(e *ColumnChangeEvent) {
+		log = append(log, fmt.Sprintf("column %d %s %v %v", e.RowIndex, e.Column, e.OldValue, e.NewValue))
+	})
+	table.OnRowDeleting(func(e *RowChangeEvent) error {
+		if e.KeyValues[0] == "second" {
+			return vetoErr
+		}
+		log = append(log, fmt.Sprint("deleting ", e.RowIndex, e.KeyValues, e.OldValues))
+		return nil
+	})
+	table.OnRowDeleted(func(e *RowChangeEvent) {
+		log = append(log, fmt.Sprint("deleted ", e.RowIndex, e.KeyValues))
+	})
+	cleared := 0
+	table.OnTableCleared(func(d *DataTable) {
+		cleared++
+	})
+
+	table.SetValues(0, "zzz", int64(10), "new")
+	table.AddValues("abc", int64(1), "x")
+	table.DeleteRow(table.Find("bbb", int64(10)))
+	want := []string{
+		"changing 1 0 [aa,\"'`a 10] [aa,\"'`a 10 test1] [zzz 10 new]",
+		"column 4 column1 aa,\"'`a zzz",
+		"column 4 column3 test1 new",
+		"changed 1 4 [zzz 10] [aa,\"'`a 10 test1] [zzz 10 new]",
+		"changing 0 -1 [] [] [abc 1 x]",
+		"changed 0 0 [abc 1] [] [abc 1 x]",
+		"deleting 1 [bbb 10] [bbb 10 test1]",
+		"deleted 1 [bbb 10]",
+	}
+	if !reflect.DeepEqual(log, want) {
+		t.Error(log)
+	}
+	//veto
+	if err := table.AddValues("abd", int64(1), "veto"); err != vetoErr || table.Find("abd", int64(1)) != -1 {
+		t.Error(err)
+	}
+	if err := table.DeleteRow(table.Find("second", int64(1))); err != vetoErr || table.Find("second", int64(1)) == -1 {
+		t.Error(err)
+	}
+	//commit and merge
+	log = nil
+	removeChanging()
+	table.AcceptChange()
+	if len(log) != 2 || log[0] != "changed 3 0 [abc 1] [] [abc 1 x]" || log[1] != "changed 3 4 [zzz 10] [aa,\"'`a 10 test1] [zzz 10 new]" {
+		t.Error(log)
+	}
+	log = nil
+	src := table.Clone()
+	src.AddValues("abc", int64(1), "y")
+	if err := table.Merge(src); err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 2 || log[0] != "column 0 column3 x y" {
+		t.Error(log)
+	}
+	table.Clear()
+	if cleared != 1 {
+		t.Error("error")
+	}
+}
@@ -0,0 +1,938 @@
+package datatable
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The expression language is a subset of the .Net DataColumn.Expression
+// syntax, used by row filters and the other expression based features:
+//
+//	column2 > 5 AND column1 LIKE 'row%' OR [my column] IS NULL
+//
+// Operators from lowest to highest precedence:
+//
+//	OR
+//	AND
+//	NOT
+//	= <> != < <= > >= LIKE, NOT LIKE, IN (...), NOT IN (...), IS [NOT] NULL
+//	+ -
+//	* / %
+//	unary -
+//
+// Literals are numbers (10 is int64, 1.5 is float64), 'strings' ('' is a
+// quote), #2006-01-02T15:04:05Z# times, TRUE, FALSE and NULL. A string
+// literal compared with a column of another type is converted by
+// DataColumn.DecodeString. LIKE accepts % or * as wildcard. The functions
+// are IIF(cond, a, b), ISNULL(v, replacement), LEN(s) and TRIM(s).
+// Comparing with NULL gives NULL, and a filter only matches the rows
+// where the expression is true.
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokTime
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+	//the identifier is quoted with [] or ``,can't be a keyword
+	quoted bool
+}
+
+func (t token) isKeyword(kw string) bool {
+	return t.kind == tokIdent && !t.quoted && strings.EqualFold(t.text, kw)
+}
+func (t token) isOp(op string) bool {
+	return t.kind == tokOp && t.text == op
+}
+
+func tokenize(src string) ([]token, error) {
+	var result []token
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		c := rs[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(rs) && (rs[i] == '_' || unicode.IsLetter(rs[i]) || unicode.IsDigit(rs[i])) {
+				i++
+			}
+			result = append(result, token{kind: tokIdent, text: string(rs[start:i]), pos: start})
+		case unicode.IsDigit(c) || c == '.' && i+1 < len(rs) && unicode.IsDigit(rs[i+1]):
+			start := i
+			for i < len(rs) && (unicode.IsDigit(rs[i]) || rs[i] == '.') {
+				i++
+			}
+			if i < len(rs) && (rs[i] == 'e' || rs[i] == 'E') {
+				i++
+				if i < len(rs) && (rs[i] == '+' || rs[i] == '-') {
+					i++
+				}
+				for i < len(rs) && unicode.IsDigit(rs[i]) {
+					i++
+				}
+			}
+			result = append(result, token{kind: tokNumber, text: string(rs[start:i]), pos: start})
+		case c == '\'':
+			start := i
+			var sb strings.Builder
+			for i++; ; i++ {
+				if i >= len(rs) {
+					return nil, fmt.Errorf("unterminated string at position %d", start)
+				}
+				if rs[i] == '\'' {
+					if i+1 < len(rs) && rs[i+1] == '\'' {
+						i++
+					} else {
+						break
+					}
+				}
+				sb.WriteRune(rs[i])
+			}
+			i++
+			result = append(result, token{kind: tokString, text: sb.String(), pos: start})
+		case c == '[' || c == '`' || c == '#':
+			end := c
+			kind := tokIdent
+			switch c {
+			case '[':
+				end = ']'
+			case '#':
+				kind = tokTime
+			}
+			start := i
+			j := i + 1
+			for j < len(rs) && rs[j] != end {
+				j++
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("missing %q at position %d", end, start)
+			}
+			result = append(result, token{kind: kind, text: string(rs[i+1 : j]), pos: start, quoted: true})
+			i = j + 1
+		default:
+			start := i
+			op := string(c)
+			if i+1 < len(rs) {
+				switch two := string(rs[i : i+2]); two {
+				case "<>", "<=", ">=", "!=", "==":
+					op = two
+				}
+			}
+			if !strings.Contains("=<>!+-*/%(),", op[:1]) || op == "!" {
+				return nil, fmt.Errorf("invalid character %q at position %d", c, start)
+			}
+			i += len([]rune(op))
+			result = append(result, token{kind: tokOp, text: op, pos: start})
+		}
+	}
+	result = append(result, token{kind: tokEOF, pos: len(rs)})
+	return result, nil
+}
+
+// exprNode is a type checked node of a compiled expression, eval gets the
+// decoded values of a row and returns nil for NULL.
+type exprNode interface {
+	//the result type,empty for the NULL literal
+	dataType() ColumnType
+	eval(values []interface{}) (interface{}, error)
+}
+
+// expression is an expression compiled against the columns of a table.
+type expression struct {
+	src  string
+	root exprNode
+	//the indexes of the columns used
+	columns []int
+}
+
+func compileExpression(d *DataTable, src string) (*expression, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %v", src, err)
+	}
+	p := &exprParser{table: d, tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = p.errorf("unexpected %q", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %v", src, err)
+	}
+	return &expression{src: src, root: root, columns: p.columns}, nil
+}
+
+// compileFilter compiles a boolean expression, used to filter the rows.
+func compileFilter(d *DataTable, src string) (*expression, error) {
+	e, err := compileExpression(d, src)
+	if err != nil {
+		return nil, err
+	}
+	if t := e.root.dataType(); t != Bool && t != "" {
+		return nil, fmt.Errorf("expression %q: the result type %s not is bool", src, t)
+	}
+	return e, nil
+}
+func (e *expression) dataType() ColumnType {
+	return e.root.dataType()
+}
+func (e *expression) eval(values []interface{}) (interface{}, error) {
+	return e.root.eval(values)
+}
+
+// match returns true only when the expression is true, NULL is false.
+func (e *expression) match(values []interface{}) (bool, error) {
+	v, err := e.root.eval(values)
+	if err != nil {
+		return false, err
+	}
+	return v == true, nil
+}
+
+type exprParser struct {
+	table   *DataTable
+	tokens  []token
+	pos     int
+	columns []int
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+func (p *exprParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, args...), p.peek().pos)
+}
+func (p *exprParser) expectOp(op string) error {
+	if !p.peek().isOp(op) {
+		return p.errorf("expected %q", op)
+	}
+	p.next()
+	return nil
+}
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newLogicNode("OR", left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("AND") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newLogicNode("AND", left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.peek().isKeyword("NOT") {
+		p.next()
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if !isBoolType(x.dataType()) {
+			return nil, fmt.Errorf("NOT need a bool operand,not %s", x.dataType())
+		}
+		return &notNode{x: x}, nil
+	}
+	return p.parseCompare()
+}
+func (p *exprParser) parseCompare() (exprNode, error) {
+	left, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	switch {
+	case isCompareOp(t):
+		p.next()
+		right, err := p.parseAdd()
+		if err != nil {
+			return nil, err
+		}
+		return newCompareNode(t.text, left, right)
+	case t.isKeyword("IS"):
+		p.next()
+		not := false
+		if p.peek().isKeyword("NOT") {
+			p.next()
+			not = true
+		}
+		if !p.peek().isKeyword("NULL") {
+			return nil, p.errorf("expected NULL")
+		}
+		p.next()
+		return &isNullNode{x: left, not: not}, nil
+	}
+	not := false
+	if t.isKeyword("NOT") {
+		if n := p.tokens[p.pos+1]; n.isKeyword("LIKE") || n.isKeyword("IN") {
+			p.next()
+			not = true
+		}
+	}
+	switch {
+	case p.peek().isKeyword("LIKE"):
+		p.next()
+		right, err := p.parseAdd()
+		if err != nil {
+			return nil, err
+		}
+		return newLikeNode(left, right, not)
+	case p.peek().isKeyword("IN"):
+		p.next()
+		if err := p.expectOp("("); err != nil {
+			return nil, err
+		}
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return newInNode(left, list, not)
+	}
+	return left, nil
+}
+
+func isCompareOp(t token) bool {
+	if t.kind != tokOp {
+		return false
+	}
+	switch t.text {
+	case "=", "==", "<>", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// parseList parses the comma separated expressions and the closing ")".
+func (p *exprParser) parseList() ([]exprNode, error) {
+	var list []exprNode
+	if p.peek().isOp(")") {
+		p.next()
+		return list, nil
+	}
+	for {
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, x)
+		if p.peek().isOp(",") {
+			p.next()
+			continue
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+}
+func (p *exprParser) parseAdd() (exprNode, error) {
+	left, err := p.parseMul()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isOp("+") || p.peek().isOp("-") {
+		op := p.next().text
+		right, err := p.parseMul()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newArithNode(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+func (p *exprParser) parseMul() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isOp("*") || p.peek().isOp("/") || p.peek().isOp("%") {
+		op := p.next().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newArithNode(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peek().isOp("-") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return newArithNode("-", &constNode{typ: Int64, value: int64(0)}, x)
+	}
+	return p.parsePrimary()
+}
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokNumber:
+		p.next()
+		if i, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return &constNode{typ: Int64, value: i}, nil
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+		}
+		return &constNode{typ: Float64, value: f}, nil
+	case tokString:
+		p.next()
+		return &constNode{typ: String, value: t.text}, nil
+	case tokTime:
+		p.next()
+		v, err := time.Parse(time.RFC3339Nano, t.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q at position %d", t.text, t.pos)
+		}
+		return &constNode{typ: Time, value: v}, nil
+	case tokOp:
+		if t.isOp("(") {
+			p.next()
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	case tokIdent:
+		p.next()
+		switch {
+		case t.isKeyword("NULL"):
+			return &constNode{}, nil
+		case t.isKeyword("TRUE"):
+			return &constNode{typ: Bool, value: true}, nil
+		case t.isKeyword("FALSE"):
+			return &constNode{typ: Bool, value: false}, nil
+		}
+		if !t.quoted && p.peek().isOp("(") {
+			p.next()
+			args, err := p.parseList()
+			if err != nil {
+				return nil, err
+			}
+			return newFuncNode(t.text, args)
+		}
+		return p.column(t)
+	}
+	return nil, p.errorf("unexpected %q", t.text)
+}
+func (p *exprParser) column(t token) (exprNode, error) {
+	i := p.table.ColumnIndex(t.text)
+	if i == -1 {
+		for j, c := range p.table.Columns {
+			if strings.EqualFold(c.Name, t.text) {
+				i = j
+				break
+			}
+		}
+	}
+	if i == -1 {
+		return nil, fmt.Errorf("the column [%s] not found at position %d", t.text, t.pos)
+	}
+	p.columns = append(p.columns, i)
+	return &columnNode{index: i, typ: p.table.Columns[i].DataType}, nil
+}
+
+func isBoolType(t ColumnType) bool {
+	return t == Bool || t == ""
+}
+func isNumberType(t ColumnType) bool {
+	return t == Int64 || t == Float64
+}
+
+// unifyTypes checks the two operands can be compared and returns the common
+// type. A string constant is converted to the type of the other operand.
+func unifyTypes(left, right *exprNode) (ColumnType, error) {
+	lt, rt := (*left).dataType(), (*right).dataType()
+	switch {
+	case lt == rt:
+		return lt, nil
+	case lt == "":
+		return rt, nil
+	case rt == "":
+		return lt, nil
+	case isNumberType(lt) && isNumberType(rt):
+		return Float64, nil
+	}
+	if c, ok := (*left).(*constNode); ok && lt == String {
+		v, err := (&DataColumn{DataType: rt}).DecodeString(c.value.(string))
+		if err != nil {
+			return "", fmt.Errorf("can't convert %q to %s: %v", c.value, rt, err)
+		}
+		*left = &constNode{typ: rt, value: v}
+		return rt, nil
+	}
+	if c, ok := (*right).(*constNode); ok && rt == String {
+		v, err := (&DataColumn{DataType: lt}).DecodeString(c.value.(string))
+		if err != nil {
+			return "", fmt.Errorf("can't convert %q to %s: %v", c.value, lt, err)
+		}
+		*right = &constNode{typ: lt, value: v}
+		return lt, nil
+	}
+	return "", fmt.Errorf("type %s and %s mismatch", lt, rt)
+}
+
+// convertNumber converts int64 to float64 when the type is Float64.
+func convertNumber(v interface{}, t ColumnType) interface{} {
+	if i, ok := v.(int64); ok && t == Float64 {
+		return float64(i)
+	}
+	return v
+}
+
+type constNode struct {
+	typ   ColumnType
+	value interface{}
+}
+
+func (n *constNode) dataType() ColumnType {
+	return n.typ
+}
+func (n *constNode) eval(values []interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type columnNode struct {
+	index int
+	typ   ColumnType
+}
+
+func (n *columnNode) dataType() ColumnType {
+	return n.typ
+}
+func (n *columnNode) eval(values []interface{}) (interface{}, error) {
+	return values[n.index], nil
+}
+
+type logicNode struct {
+	op          string
+	left, right exprNode
+}
+
+func newLogicNode(op string, left, right exprNode) (exprNode, error) {
+	if !isBoolType(left.dataType()) || !isBoolType(right.dataType()) {
+		return nil, fmt.Errorf("%s need bool operands,not %s and %s", op, left.dataType(), right.dataType())
+	}
+	return &logicNode{op: op, left: left, right: right}, nil
+}
+func (n *logicNode) dataType() ColumnType {
+	return Bool
+}
+func (n *logicNode) eval(values []interface{}) (interface{}, error) {
+	l, err := n.left.eval(values)
+	if err != nil {
+		return nil, err
+	}
+	//short circuit
+	if n.op == "AND" && l == false || n.op == "OR" && l == true {
+		return l, nil
+	}
+	r, err := n.right.eval(values)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "AND" && r == false || n.op == "OR" && r == true {
+		return r, nil
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	return r, nil
+}
+
+type notNode struct {
+	x exprNode
+}
+
+func (n *notNode) dataType() ColumnType {
+	return Bool
+}
+func (n *notNode) eval(values []interface{}) (interface{}, error) {
+	v, err := n.x.eval(values)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return !v.(bool), nil
+}
+
+type compareNode struct {
+	op          string
+	typ         ColumnType
+	left, right exprNode
+}
+
+func newCompareNode(op string, left, right exprNode) (exprNode, error) {
+	t, err := unifyTypes(&left, &right)
+	if err != nil {
+		return nil, fmt.Errorf("can't compare(%s): %v", op, err)
+	}
+	return &compareNode{op: op, typ: t, left: left, right: right}, nil
+}
+func (n *compareNode) dataType() ColumnType {
+	return Bool
+}
+func (n *compareNode) eval(values []interface{}) (interface{}, error) {
+	l, err := n.left.eval(values)
+	if err != nil || l == nil {
+		return nil, err
+	}
+	r, err := n.right.eval(values)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	c := cmpValue(convertNumber(l, n.typ), convertNumber(r, n.typ))
+	switch n.op {
+	case "=", "==":
+		return c == 0, nil
+	case "<>", "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+type isNullNode struct {
+	x   exprNode
+	not bool
+}
+
+func (n *isNullNode) dataType() ColumnType {
+	return Bool
+}
+func (n *isNullNode) eval(values []interface{}) (interface{}, error) {
+	v, err := n.x.eval(values)
+	if err != nil {
+		return nil, err
+	}
+	return (v == nil) != n.not, nil
+}
+
+type likeNode struct {
+	x, pattern exprNode
+	//the compiled constant pattern
+	re  *regexp.Regexp
+	not bool
+}
+
+func newLikeNode(x, pattern exprNode, not bool) (exprNode, error) {
+	if x.dataType() != String && x.dataType() != "" ||
+		pattern.dataType() != String && pattern.dataType() != "" {
+		return nil, fmt.Errorf("LIKE need string operands,not %s and %s", x.dataType(), pattern.dataType())
+	}
+	n := &likeNode{x: x, pattern: pattern, not: not}
+	if c, ok := pattern.(*constNode); ok && c.value != nil {
+		n.re = likeRegexp(c.value.(string))
+	}
+	return n, nil
+}
+
+// likeRegexp converts the LIKE pattern to regexp,% and * match any
+// characters, [x] match the x itself.
+func likeRegexp(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("(?s)^")
+	rs := []rune(pattern)
+	for i := 0; i < len(rs); i++ {
+		switch rs[i] {
+		case '%', '*':
+			sb.WriteString(".*")
+		case '[':
+			j := i + 1
+			for j < len(rs) && rs[j] != ']' {
+				j++
+			}
+			if j < len(rs) {
+				sb.WriteString(regexp.QuoteMeta(string(rs[i+1 : j])))
+				i = j
+				continue
+			}
+			sb.WriteString(regexp.QuoteMeta(string(rs[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(rs[i])))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+func (n *likeNode) dataType() ColumnType {
+	return Bool
+}
+func (n *likeNode) eval(values []interface{}) (interface{}, error) {
+	v, err := n.x.eval(values)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	re := n.re
+	if re == nil {
+		p, err := n.pattern.eval(values)
+		if err != nil || p == nil {
+			return nil, err
+		}
+		re = likeRegexp(p.(string))
+	}
+	return re.MatchString(v.(string)) != n.not, nil
+}
+
+type inNode struct {
+	x    exprNode
+	typ  ColumnType
+	list []exprNode
+	not  bool
+}
+
+func newInNode(x exprNode, list []exprNode, not bool) (exprNode, error) {
+	if len(list) == 0 {
+		return nil, fmt.Errorf("IN need at least one value")
+	}
+	typ := x.dataType()
+	for i := range list {
+		t, err := unifyTypes(&x, &list[i])
+		if err != nil {
+			return nil, fmt.Errorf("IN: %v", err)
+		}
+		if typ == "" || t == Float64 {
+			typ = t
+		}
+	}
+	return &inNode{x: x, typ: typ, list: list, not: not}, nil
+}
+func (n *inNode) dataType() ColumnType {
+	return Bool
+}
+func (n *inNode) eval(values []interface{}) (interface{}, error) {
+	v, err := n.x.eval(values)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	v = convertNumber(v, n.typ)
+	hasNull := false
+	for _, e := range n.list {
+		ev, err := e.eval(values)
+		if err != nil {
+			return nil, err
+		}
+		if ev == nil {
+			hasNull = true
+			continue
+		}
+		if cmpValue(v, convertNumber(ev, n.typ)) == 0 {
+			return !n.not, nil
+		}
+	}
+	if hasNull {
+		return nil, nil
+	}
+	return n.not, nil
+}
+
+type arithNode struct {
+	op          string
+	typ         ColumnType
+	left, right exprNode
+}
+
+func newArithNode(op string, left, right exprNode) (exprNode, error) {
+	lt, rt := left.dataType(), right.dataType()
+	var typ ColumnType
+	switch {
+	case op == "+" && (lt == String || rt == String) && (lt == String || lt == "") && (rt == String || rt == ""):
+		typ = String
+	case (isNumberType(lt) || lt == "") && (isNumberType(rt) || rt == ""):
+		typ = Int64
+		if lt == Float64 || rt == Float64 {
+			typ = Float64
+		}
+	default:
+		return nil, fmt.Errorf("operator %s not support the type %s and %s", op, lt, rt)
+	}
+	return &arithNode{op: op, typ: typ, left: left, right: right}, nil
+}
+func (n *arithNode) dataType() ColumnType {
+	return n.typ
+}
+func (n *arithNode) eval(values []interface{}) (interface{}, error) {
+	l, err := n.left.eval(values)
+	if err != nil || l == nil {
+		return nil, err
+	}
+	r, err := n.right.eval(values)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	switch n.typ {
+	case String:
+		return l.(string) + r.(string), nil
+	case Int64:
+		a, b := l.(int64), r.(int64)
+		switch n.op {
+		case "+":
+			return a + b, nil
+		case "-":
+			return a - b, nil
+		case "*":
+			return a * b, nil
+		}
+		if b == 0 {
+			return nil, fmt.Errorf("divide by zero")
+		}
+		if n.op == "/" {
+			return a / b, nil
+		}
+		return a % b, nil
+	default:
+		a, b := convertNumber(l, Float64).(float64), convertNumber(r, Float64).(float64)
+		switch n.op {
+		case "+":
+			return a + b, nil
+		case "-":
+			return a - b, nil
+		case "*":
+			return a * b, nil
+		case "/":
+			return a / b, nil
+		default:
+			return math.Mod(a, b), nil
+		}
+	}
+}
+
+type funcNode struct {
+	name string
+	typ  ColumnType
+	args []exprNode
+}
+
+func newFuncNode(name string, args []exprNode) (exprNode, error) {
+	name = strings.ToUpper(name)
+	n := &funcNode{name: name, args: args}
+	argc := map[string]int{"IIF": 3, "ISNULL": 2, "LEN": 1, "TRIM": 1}
+	c, ok := argc[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	if len(args) != c {
+		return nil, fmt.Errorf("function %s need %d arguments,not %d", name, c, len(args))
+	}
+	switch name {
+	case "IIF":
+		if !isBoolType(args[0].dataType()) {
+			return nil, fmt.Errorf("IIF need a bool condition,not %s", args[0].dataType())
+		}
+		t, err := unifyTypes(&n.args[1], &n.args[2])
+		if err != nil {
+			return nil, fmt.Errorf("IIF: %v", err)
+		}
+		n.typ = t
+	case "ISNULL":
+		t, err := unifyTypes(&n.args[0], &n.args[1])
+		if err != nil {
+			return nil, fmt.Errorf("ISNULL: %v", err)
+		}
+		n.typ = t
+	case "LEN", "TRIM":
+		if t := args[0].dataType(); t != String && t != "" {
+			return nil, fmt.Errorf("%s need a string argument,not %s", name, t)
+		}
+		n.typ = String
+		if name == "LEN" {
+			n.typ = Int64
+		}
+	}
+	return n, nil
+}
+func (n *funcNode) dataType() ColumnType {
+	return n.typ
+}
+func (n *funcNode) eval(values []interface{}) (interface{}, error) {
+	v, err := n.args[0].eval(values)
+	if err != nil {
+		return nil, err
+	}
+	switch n.name {
+	case "IIF":
+		arg := n.args[2]
+		if v == true {
+			arg = n.args[1]
+		}
+		if v, err = arg.eval(values); err != nil {
+			return nil, err
+		}
+	case "ISNULL":
+		if v == nil {
+			if v, err = n.args[1].eval(values); err != nil {
+				return nil, err
+			}
+		}
+	case "LEN":
+		if v != nil {
+			return int64(len([]rune(v.(string)))), nil
+		}
+	case "TRIM":
+		if v != nil {
+			return strings.TrimSpace(v.(string)), nil
+		}
+	}
+	return convertNumber(v, n.typ), nil
+}
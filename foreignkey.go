@@ -0,0 +1,255 @@
+package datatable
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Rule is the action on the child rows when the parent row is deleted or
+// its key is changed, like the .Net Rule.
+type Rule int
+
+const (
+	//delete the child rows or change their keys
+	RuleCascade Rule = iota
+	//set the child columns to null
+	RuleSetNull
+	//set the child columns to the default values
+	RuleSetDefault
+	//reject the change of the parent row having child rows
+	RuleRestrict
+	//leave the child rows as they are, they lose the parent row
+	RuleNoAction
+)
+
+// ConstraintError is the violation of a constraint, Key is the values of
+// the constraint columns, the primary key values for the check
+// constraint. RowIndex is the violating row, -1 for the row being added.
+type ConstraintError struct {
+	Constraint string
+	Table      string
+	RowIndex   int
+	Key        []interface{}
+	Message    string
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("constraint [%s] of table [%s] violated by key %v: %s", e.Constraint, e.Table, e.Key, e.Message)
+}
+
+// ForeignKeyConstraint enforces the relation: a child row must have the
+// parent row unless a child column is null, the rules apply to the child
+// rows when the parent row is deleted or its key is changed.
+type ForeignKeyConstraint struct {
+	Relation   *DataRelation
+	DeleteRule Rule
+	UpdateRule Rule
+}
+
+// Name returns the name of the constraint, the same as the relation.
+func (c *ForeignKeyConstraint) Name() string {
+	return c.Relation.Name
+}
+
+func (c *ForeignKeyConstraint) violation(table *DataTable, rowIndex int, key []interface{}, msg string) *ConstraintError {
+	return &ConstraintError{Constraint: c.Name(), Table: table.TableName, RowIndex: rowIndex, Key: key, Message: msg}
+}
+
+// childKeyOf returns the child columns of the decoded values, nil if a
+// value is null.
+func (c *ForeignKeyConstraint) childKeyOf(values []interface{}) []interface{} {
+	child := c.Relation.ChildTable
+	key := make([]interface{}, len(c.Relation.ChildColumns))
+	for i, col := range c.Relation.ChildColumns {
+		if key[i] = values[child.ColumnIndex(col)]; key[i] == nil {
+			return nil
+		}
+	}
+	return key
+}
+
+// checkRow checks the parent row of the child values exists, it is a
+// constraint of the child table.
+func (c *ForeignKeyConstraint) checkRow(values []interface{}, trueIndex int) *ConstraintError {
+	if key := c.childKeyOf(values); key != nil && c.Relation.ParentTable.Find(key...) == -1 {
+		return c.violation(c.Relation.ChildTable, -1, key, "the parent row not found")
+	}
+	return nil
+}
+func (c *ForeignKeyConstraint) usesColumn(col int) bool {
+	return false
+}
+
+// apply applies the rule to the child rows of the parent key, newKey is
+// the new parent key of the update.
+func (c *ForeignKeyConstraint) apply(rule Rule, rowIndex int, key, newKey []interface{}) error {
+	child := c.Relation.ChildTable
+	x, err := c.Relation.childIndex()
+	if err != nil {
+		return err
+	}
+	rows := x.rowIndexes(key)
+	if len(rows) == 0 || rule == RuleNoAction {
+		return nil
+	}
+	if rule == RuleRestrict {
+		return c.violation(c.Relation.ParentTable, rowIndex, key, "the child rows exist")
+	}
+	//the row indexes change by every edit, so find the rows again
+	for len(rows) > 0 {
+		i := rows[0]
+		if rule == RuleCascade && newKey == nil {
+			err = child.DeleteRow(i)
+		} else {
+			values := child.GetValues(i)
+			for j, col := range c.Relation.ChildColumns {
+				k := child.ColumnIndex(col)
+				switch rule {
+				case RuleCascade:
+					values[k] = newKey[j]
+				case RuleSetNull:
+					values[k] = nil
+				case RuleSetDefault:
+					values[k] = child.Columns[k].defaultValue()
+				}
+			}
+			err = child.SetValues(i, values...)
+		}
+		if err != nil {
+			return err
+		}
+		next := x.rowIndexes(key)
+		if len(next) >= len(rows) {
+			//the default values equal the key
+			break
+		}
+		rows = next
+	}
+	return nil
+}
+
+// applyTo applies the rule of the parent change to the child rows, the
+// edits of the child table are added to the journal.
+func (c *ForeignKeyConstraint) applyTo(j *journal, rowIndex int, key, newKey []interface{}) error {
+	rule := c.DeleteRule
+	if newKey != nil {
+		if reflect.DeepEqual(key, newKey) {
+			return nil
+		}
+		rule = c.UpdateRule
+	}
+	child := c.Relation.ChildTable
+	saved := child.journal
+	child.journal = j
+	defer func() {
+		child.journal = saved
+	}()
+	return c.apply(rule, rowIndex, key, newKey)
+}
+
+// journal is the edits of the tables by a parent change and the foreign
+// key rules applied to the child rows, rolled back if a rule fails.
+type journal struct {
+	entries []journalEntry
+}
+type journalEntry struct {
+	table *DataTable
+	entry *historyEntry
+}
+
+// rollbackTo reverts the edits after the mark in the reverse order, they
+// are removed from the undo history of the tables too.
+func (j *journal) rollbackTo(mark int) {
+	for i := len(j.entries) - 1; i >= mark; i-- {
+		d, e := j.entries[i].table, j.entries[i].entry
+		d.revert(e)
+		if n := len(d.history.undo); n > 0 && d.history.undo[n-1] == e {
+			d.history.undo = d.history.undo[:n-1]
+		}
+		d.updateChanged()
+	}
+	j.entries = j.entries[:mark]
+}
+
+// journaling reports whether the edit of the row needs the history entry,
+// for the undo history or the rollback of the foreign key rules.
+func (d *DataTable) journaling() bool {
+	return d.history.recording() || d.journal != nil || len(d.foreignKeys) > 0
+}
+
+// cascade applies the foreign key rules of the table as the parent after
+// the row at rowIndex is deleted or its key is changed from key to newKey,
+// newKey is nil for the delete. If a rule fails, the child rows edited
+// and the row are rolled back.
+func (d *DataTable) cascade(entry *historyEntry, rowIndex int, key, newKey []interface{}) error {
+	j := d.journal
+	if j == nil {
+		j = &journal{}
+	}
+	mark := len(j.entries)
+	j.entries = append(j.entries, journalEntry{table: d, entry: entry})
+	for _, c := range d.foreignKeys {
+		if err := c.applyTo(j, rowIndex, key, newKey); err != nil {
+			j.rollbackTo(mark)
+			return err
+		}
+	}
+	return nil
+}
+
+// AddForeignKey enforces the relation as a foreign key constraint with
+// the rules, it is a constraint of the child table and the existing child
+// rows must have the parent rows unless the constraints of the child table
+// are disabled. A new or changed child row must have the parent row unless
+// a child column is null.
+//
+// The rules are applied by DeleteRow and SetValues of the parent table
+// after the parent change passed the handlers and the constraints, if a
+// rule fails the child rows edited and the parent row are rolled back,
+// the events already fired for the child rows are not fired again.
+// Clear, RejectChanges, Undo, Redo, RollbackTo and the reading of the
+// parent table replace the rows without the rules and can leave the child
+// rows without the parent rows, call EnableConstraints of the child table
+// to report them.
+func (s *DataSet) AddForeignKey(relation string, deleteRule, updateRule Rule) (*ForeignKeyConstraint, error) {
+	r := s.Relation(relation)
+	if r == nil {
+		return nil, RelationNotFoundError(relation)
+	}
+	if r.foreignKey != nil {
+		return nil, fmt.Errorf("the relation [%s] has foreign key", relation)
+	}
+	c := &ForeignKeyConstraint{Relation: r, DeleteRule: deleteRule, UpdateRule: updateRule}
+	if err := r.ChildTable.addConstraint(c); err != nil {
+		return nil, err
+	}
+	r.ParentTable.foreignKeys = append(r.ParentTable.foreignKeys, c)
+	r.foreignKey = c
+	return c, nil
+}
+
+// ForeignKey returns the foreign key constraint of the relation, nil if
+// none.
+func (r *DataRelation) ForeignKey() *ForeignKeyConstraint {
+	return r.foreignKey
+}
+
+// RemoveForeignKey stops enforcing the foreign key of the relation.
+func (s *DataSet) RemoveForeignKey(relation string) error {
+	r := s.Relation(relation)
+	if r == nil {
+		return RelationNotFoundError(relation)
+	}
+	if r.foreignKey != nil {
+		parent := r.ParentTable
+		for i, c := range parent.foreignKeys {
+			if c == r.foreignKey {
+				parent.foreignKeys = append(parent.foreignKeys[:i], parent.foreignKeys[i+1:]...)
+				break
+			}
+		}
+		r.ChildTable.removeConstraint(r.foreignKey)
+		r.foreignKey = nil
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package datatable
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestForeignKey(t *testing.T) {
+	ds := createOrderDataSet()
+	orders, lines := ds.Table("orders"), ds.Table("lines")
+	lines.AddValues(int64(9), int64(9), "orphan")
+	if _, err := ds.AddForeignKey("order_lines", RuleCascade, RuleCascade); err == nil {
+		t.Error("the orphan row must be error")
+	}
+	lines.DeleteRow(lines.Find(int64(9)))
+	fk, err := ds.AddForeignKey("order_lines", RuleCascade, RuleCascade)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the child must have the parent
+	err = lines.AddValues(int64(9), int64(9), "orphan")
+	if cerr, ok := err.(*ConstraintError); !ok || cerr.Constraint != "order_lines" || cerr.Key[0] != int64(9) || cerr.Table != "lines" {
+		t.Error(err)
+	}
+	if err := lines.SetValues(lines.Find(int64(1)), int64(1), int64(9), "x"); err == nil {
+		t.Error("must be error")
+	}
+	if err := lines.AddValues(int64(9), nil, "no order"); err != nil {
+		t.Error(err)
+	}
+	//cascade update and delete
+	if err := orders.SetValues(orders.Find(int64(1)), int64(10), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if rows, _ := ds.ChildRows("order_lines", orders.Find(int64(10))); len(rows) != 2 {
+		t.Error(rows)
+	}
+	if chg := lines.GetChange(); len(chg.UpdateRows) != 2 || len(chg.InsertRows) != 1 {
+		t.Error(chg)
+	}
+	if err := orders.DeleteRow(orders.Find(int64(10))); err != nil {
+		t.Fatal(err)
+	}
+	if lines.RowCount() != 3 || len(lines.GetChange().DeleteRows) != 2 {
+		t.Error(lines.AsCsv())
+	}
+	//set null
+	fk.DeleteRule = RuleSetNull
+	fk.UpdateRule = RuleRestrict
+	if err := orders.SetValues(orders.Find(int64(2)), int64(20), "b"); err == nil || orders.Find(int64(2)) == -1 {
+		t.Error("must be error")
+	}
+	if err := orders.DeleteRow(orders.Find(int64(2))); err != nil {
+		t.Fatal(err)
+	}
+	if v := lines.GetValue(lines.Find(int64(2)), 1); v != nil {
+		t.Error(v)
+	}
+	//restrict
+	orders.AddValues(int64(3), "c")
+	lines.AddValues(int64(5), int64(3), "v")
+	fk.DeleteRule = RuleRestrict
+	if err := orders.DeleteRow(orders.Find(int64(3))); err == nil || orders.Find(int64(3)) == -1 {
+		t.Error("must be error")
+	}
+	//no action
+	fk.DeleteRule = RuleNoAction
+	if err := orders.DeleteRow(orders.Find(int64(3))); err != nil || lines.Find(int64(5)) == -1 {
+		t.Error(err)
+	}
+	if err := ds.RemoveForeignKey("order_lines"); err != nil || ds.Relation("order_lines").ForeignKey() != nil {
+		t.Error(err)
+	}
+	if err := lines.AddValues(int64(10), int64(99), "free"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestForeignKeyRollback(t *testing.T) {
+	ds := createOrderDataSet()
+	orders, lines := ds.Table("orders"), ds.Table("lines")
+	if _, err := ds.AddForeignKey("order_lines", RuleCascade, RuleCascade); err != nil {
+		t.Fatal(err)
+	}
+	lines.SetHistoryDepth(10)
+	want := lines.AsCsv()
+	//a later veto of the delete keeps the child rows
+	veto := errors.New("veto")
+	remove := orders.OnRowDeleting(func(e *RowChangeEvent) error {
+		return veto
+	})
+	if err := orders.DeleteRow(orders.Find(int64(1))); err != veto || lines.AsCsv() != want {
+		t.Error(err, lines.AsCsv())
+	}
+	remove()
+	//the failing change of the parent is not applied to the child rows
+	if _, err := orders.AddCheckConstraint("ck", "id < 100"); err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.SetValues(orders.Find(int64(2)), int64(200), "b"); err == nil || orders.Find(int64(2)) == -1 {
+		t.Error("must be error")
+	}
+	if lines.AsCsv() != want || lines.HasChange() {
+		t.Error(lines.AsCsv())
+	}
+	//a rule failing partway rolls back the child rows already changed
+	notes := NewDataTable("notes")
+	notes.AddColumn(NewInt64Column("id"))
+	notes.AddColumn(Int64Column("order_id", true))
+	notes.SetPK("id")
+	notes.AddValues(int64(1), int64(1))
+	ds.AddTable(notes)
+	if _, err := ds.AddRelation("order_notes", "orders", "notes", "order_id"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.AddForeignKey("order_notes", RuleSetNull, RuleCascade); err != nil {
+		t.Fatal(err)
+	}
+	ds.Relation("order_lines").ForeignKey().DeleteRule = RuleSetNull
+	if err := orders.DeleteRow(orders.Find(int64(1))); err == nil || orders.Find(int64(1)) == -1 || orders.HasChange() {
+		t.Error("must be error")
+	}
+	if lines.AsCsv() != want || lines.HasChange() || lines.CanUndo() {
+		t.Error(lines.AsCsv())
+	}
+	//the cascade passing all checks
+	if err := orders.SetValues(orders.Find(int64(1)), int64(10), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if rows, _ := ds.ChildRows("order_lines", orders.Find(int64(10))); len(rows) != 2 || notes.GetValue(0, 1) != int64(10) {
+		t.Error(rows)
+	}
+}
+
+func TestForeignKeyBulkParentChange(t *testing.T) {
+	ds := createOrderDataSet()
+	orders, lines := ds.Table("orders"), ds.Table("lines")
+	if _, err := ds.AddForeignKey("order_lines", RuleCascade, RuleCascade); err != nil {
+		t.Fatal(err)
+	}
+	//the clear of the parent doesn't apply the rules
+	orders.Clear()
+	if lines.RowCount() != 4 {
+		t.Error(lines.AsCsv())
+	}
+	err := lines.EnableConstraints()
+	if verr, ok := err.(*ConstraintViolationsError); !ok || len(verr.Violations) != 3 {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,262 @@
+package datatable
+
+import "errors"
+
+// HistoryEmptyError is returned by Undo or Redo when there is nothing to
+// undo or redo.
+var HistoryEmptyError = errors.New("no history to undo or redo")
+
+type historyOp byte
+
+const (
+	historyAdd historyOp = iota
+	historySet
+	historyDelete
+	historyColumn
+)
+
+// rowState is the stored values and the change state of a row.
+type rowState struct {
+	values []interface{}
+	status byte
+	origin []interface{}
+}
+
+// historyEntry is an edit of the table, before is the row state before
+// set or delete, after is the row state taken by undo for redo.
+type historyEntry struct {
+	op        historyOp
+	trueIndex int
+	before    rowState
+	after     rowState
+	//the delete added the row to the deleted rows
+	deleted bool
+	column  *DataColumn
+}
+
+// history is the journal of the edits, disabled when depth is 0.
+type history struct {
+	depth     int
+	undo      []*historyEntry
+	redo      []*historyEntry
+	replaying bool
+}
+
+func (h *history) recording() bool {
+	return h.depth > 0 && !h.replaying
+}
+func (h *history) clear() {
+	h.undo = nil
+	h.redo = nil
+}
+
+// SetHistoryDepth enables the undo history of AddValues, SetValues,
+// DeleteRow and AddColumn with the max count of the edits, 0 disables it.
+// The history is cleared by AcceptChange, RejectChanges, Clear, SetPK,
+// RollbackTo and the row state operations.
+func (d *DataTable) SetHistoryDepth(depth int) {
+	if depth < 0 {
+		depth = 0
+	}
+	d.history.depth = depth
+	if len(d.history.undo) > depth {
+		d.history.undo = d.history.undo[len(d.history.undo)-depth:]
+	}
+	if len(d.history.redo) > depth {
+		d.history.redo = d.history.redo[len(d.history.redo)-depth:]
+	}
+	if depth == 0 {
+		d.history.clear()
+	}
+}
+
+// HistoryDepth returns the max count of the edits in the undo history.
+func (d *DataTable) HistoryDepth() int {
+	return d.history.depth
+}
+
+// CanUndo reports whether there is an edit to undo.
+func (d *DataTable) CanUndo() bool {
+	return len(d.history.undo) > 0
+}
+
+// CanRedo reports whether there is an undone edit to redo.
+func (d *DataTable) CanRedo() bool {
+	return len(d.history.redo) > 0
+}
+
+func (d *DataTable) recordHistory(e *historyEntry) {
+	if !d.history.recording() {
+		return
+	}
+	d.history.undo = append(d.history.undo, e)
+	if len(d.history.undo) > d.history.depth {
+		d.history.undo = d.history.undo[1:]
+	}
+	d.history.redo = nil
+}
+
+// rowStateOf returns the state of the row at the true index.
+func (d *DataTable) rowStateOf(trueIndex int) rowState {
+	return rowState{
+		values: d.currentRows.GetRow(trueIndex),
+		status: d.rowStatus[trueIndex],
+		origin: d.originData[trueIndex],
+	}
+}
+
+// setRowState sets the values and the change state of the row at the
+// true index.
+func (d *DataTable) setRowState(trueIndex int, state rowState) error {
+	if err := d.setValues(d.rowIndexOf(trueIndex), d.decodeValues(state.values)); err != nil {
+		return err
+	}
+	d.setRowStatus(trueIndex, state.status)
+	d.originData[trueIndex] = state.origin
+	return nil
+}
+
+// swapRows swaps the storage of two rows.
+func (d *DataTable) swapRows(i, j int) {
+	rowI, rowJ := d.currentRows.GetRow(i), d.currentRows.GetRow(j)
+	d.currentRows.SetRow(i, rowJ)
+	d.currentRows.SetRow(j, rowI)
+	d.rowStatus[i], d.rowStatus[j] = d.rowStatus[j], d.rowStatus[i]
+	d.originData[i], d.originData[j] = d.originData[j], d.originData[i]
+	swap := func(index []int) {
+		for k, v := range index {
+			switch v {
+			case i:
+				index[k] = j
+			case j:
+				index[k] = i
+			}
+		}
+	}
+	swap(d.primaryIndexes.index)
+	for _, x := range d.indexes {
+		swap(x.index)
+	}
+}
+
+// removeLastColumn removes the last column and the indexes and the
+// constraints on it.
+func (d *DataTable) removeLastColumn() {
+	i := len(d.Columns) - 1
+	var constraints []tableConstraint
+	for _, c := range d.constraints {
+		if !c.usesColumn(i) {
+			constraints = append(constraints, c)
+		}
+	}
+	d.constraints = constraints
+	d.currentRows.data = d.currentRows.data[:i]
+	d.deleteRows.data = d.deleteRows.data[:i]
+	for j, v := range d.originData {
+		if d.rowStatus[j] == UPDATE {
+			d.originData[j] = v[:i:i]
+		} else {
+			d.originData[j] = nil
+		}
+	}
+	d.Columns = d.Columns[:i]
+	var indexes []*dataIndex
+	for _, x := range d.indexes {
+		used := false
+		for _, c := range x.columns {
+			used = used || c == i
+		}
+		if !used {
+			indexes = append(indexes, x)
+		}
+	}
+	d.indexes = indexes
+	d.version++
+}
+
+// Undo reverts the last edit in the history, the row state and the
+// deleted rows are restored too. The revert restores the stored state
+// directly, the events are not fired and the constraints and the foreign
+// keys are not checked or applied.
+func (d *DataTable) Undo() error {
+	if !d.CanUndo() {
+		return HistoryEmptyError
+	}
+	e := d.history.undo[len(d.history.undo)-1]
+	d.history.replaying = true
+	defer func() {
+		d.history.replaying = false
+	}()
+	if err := d.revert(e); err != nil {
+		return err
+	}
+	d.history.undo = d.history.undo[:len(d.history.undo)-1]
+	d.history.redo = append(d.history.redo, e)
+	d.updateChanged()
+	return nil
+}
+
+// revert restores the table to the state before the edit, the state after
+// is kept by the edit for redo.
+func (d *DataTable) revert(e *historyEntry) error {
+	switch e.op {
+	case historyAdd:
+		e.after = d.rowStateOf(e.trueIndex)
+		d.removeRow(d.rowIndexOf(e.trueIndex), false)
+	case historySet:
+		e.after = d.rowStateOf(e.trueIndex)
+		if err := d.setRowState(e.trueIndex, e.before); err != nil {
+			return err
+		}
+	case historyDelete:
+		if e.deleted {
+			d.deleteRows.Delete(d.deleteRows.Count() - 1)
+		}
+		if err := d.addValues(d.decodeValues(e.before.values)); err != nil {
+			return err
+		}
+		//the last row was moved to the deleted position, move it back
+		last := d.currentRows.Count() - 1
+		d.setRowStatus(last, e.before.status)
+		d.originData[last] = e.before.origin
+		if e.trueIndex != last {
+			d.swapRows(e.trueIndex, last)
+		}
+	case historyColumn:
+		d.removeLastColumn()
+	}
+	return nil
+}
+
+// Redo applies the last undone edit again, like Undo without the events,
+// the constraints and the foreign keys.
+func (d *DataTable) Redo() error {
+	if !d.CanRedo() {
+		return HistoryEmptyError
+	}
+	e := d.history.redo[len(d.history.redo)-1]
+	d.history.replaying = true
+	defer func() {
+		d.history.replaying = false
+	}()
+	switch e.op {
+	case historyAdd:
+		if err := d.addValues(d.decodeValues(e.after.values)); err != nil {
+			return err
+		}
+		d.setRowStatus(e.trueIndex, e.after.status)
+		d.originData[e.trueIndex] = e.after.origin
+	case historySet:
+		if err := d.setRowState(e.trueIndex, e.after); err != nil {
+			return err
+		}
+	case historyDelete:
+		d.removeRow(d.rowIndexOf(e.trueIndex), true)
+	case historyColumn:
+		d.AddColumn(e.column)
+	}
+	d.history.redo = d.history.redo[:len(d.history.redo)-1]
+	d.history.undo = append(d.history.undo, e)
+	d.updateChanged()
+	return nil
+}
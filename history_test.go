@@ -0,0 +1,97 @@
+package datatable
+
+import (
+	"testing"
+)
+
+func TestUndoRedo(t *testing.T) {
+	table := NewDataTable("table1")
+	table.AddColumn(NewStringColumn("column1"))
+	table.AddColumn(NewInt64Column("column2"))
+	table.AddValues("a", int64(1))
+	table.AddValues("b", int64(2))
+	table.AddValues("c", int64(3))
+	table.AcceptChange()
+	table.SetHistoryDepth(10)
+	if table.CanUndo() || table.Undo() != HistoryEmptyError {
+		t.Error("error")
+	}
+	states := []string{table.AsCsv()}
+	changes := []int{0}
+	edit := func(f func()) {
+		f()
+		states = append(states, table.AsCsv())
+		changes = append(changes, table.GetChange().RowCount)
+	}
+	edit(func() { table.SetValues(0, "a", int64(10)) })
+	edit(func() { table.AddValues("d", int64(4)) })
+	edit(func() { table.DeleteRow(0) })
+	edit(func() { table.DeleteRow(table.RowCount() - 1) })
+	edit(func() { table.AddColumn(NewStringColumn("column3")) })
+	edit(func() { table.SetValues(1, "b", int64(20), "x") })
+	for i := len(states) - 2; i >= 0; i-- {
+		if err := table.Undo(); err != nil {
+			t.Fatal(err)
+		}
+		if table.AsCsv() != states[i] || table.GetChange().RowCount != changes[i] {
+			t.Error(i, table.AsCsv(), table.GetChange().RowCount)
+		}
+	}
+	if table.HasChange() || table.CanUndo() || !table.CanRedo() {
+		t.Error("error")
+	}
+	for i := 1; i < len(states); i++ {
+		if err := table.Redo(); err != nil {
+			t.Fatal(err)
+		}
+		if table.AsCsv() != states[i] || table.GetChange().RowCount != changes[i] {
+			t.Error(i, table.AsCsv(), table.GetChange().RowCount)
+		}
+	}
+	if table.Redo() != HistoryEmptyError {
+		t.Error("error")
+	}
+	table.AcceptChange()
+	if table.CanUndo() {
+		t.Error("error")
+	}
+}
+
+func TestUndoWithPrimaryKey(t *testing.T) {
+	table := CreateTestData()
+	table.CreateIndex("idx", []string{"column3"}, false)
+	table.AcceptChange()
+	table.SetHistoryDepth(2)
+	want := table.AsCsv()
+	table.AddValues("zzz", int64(1), "test1")
+	table.SetValues(table.Find("first", int64(10)), "a", int64(10), "changed")
+	table.DeleteRow(table.Find("bbb", int64(10)))
+	//the first edit is dropped by the depth
+	table.Undo()
+	table.Undo()
+	if table.Undo() != HistoryEmptyError || table.Find("zzz", int64(1)) == -1 {
+		t.Error("error")
+	}
+	table.DeleteRow(table.Find("zzz", int64(1)))
+	if table.CanRedo() || table.AsCsv() != want || table.HasChange() {
+		t.Error(table.AsCsv())
+	}
+	if table.Find("first", int64(10)) == -1 || len(table.SearchBy("idx", "test1")) != 4 {
+		t.Error("error")
+	}
+	//the lower depth trims the redo too
+	table.SetHistoryDepth(3)
+	table.AddValues("r1", int64(1), "test1")
+	table.AddValues("r2", int64(1), "test1")
+	table.AddValues("r3", int64(1), "test1")
+	table.Undo()
+	table.Undo()
+	table.Undo()
+	table.SetHistoryDepth(1)
+	if table.Redo() != nil || table.Redo() != HistoryEmptyError {
+		t.Error("error")
+	}
+	if table.Find("r1", int64(1)) == -1 || table.Find("r2", int64(1)) != -1 || table.Find("r3", int64(1)) != -1 {
+		t.Error(table.AsCsv())
+	}
+}
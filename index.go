@@ -0,0 +1,249 @@
+package datatable
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// IndexExistsError is returned when create an index with the name exists.
+var IndexExistsError = errors.New("the index exists")
+
+// IndexNotFoundError returns the error of the index not found.
+func IndexNotFoundError(name string) error {
+	return fmt.Errorf("the index [%s] not found", name)
+}
+
+// IndexKeyExistsError returns the error of a duplicate key of the unique
+// index, it wraps the KeyValueExists.
+func IndexKeyExistsError(name string, key []interface{}) error {
+	return fmt.Errorf("the key %v of index [%s]: %w", key, name, KeyValueExists)
+}
+
+// dataIndex is a secondary index of the table, it holds the true row
+// indexes sorted by the key columns.
+type dataIndex struct {
+	dataTable *DataTable
+	name      string
+	columns   []int
+	unique    bool
+	index     []int
+}
+
+func (x *dataIndex) keyOf(trueIndex int) []interface{} {
+	result := make([]interface{}, len(x.columns))
+	for i, c := range x.columns {
+		result[i] = x.dataTable.Columns[c].Decode(x.dataTable.currentRows.Get(c, trueIndex))
+	}
+	return result
+}
+
+// keyOfStore returns the key of stored row values.
+func (x *dataIndex) keyOfStore(values []interface{}) []interface{} {
+	result := make([]interface{}, len(x.columns))
+	for i, c := range x.columns {
+		result[i] = x.dataTable.Columns[c].Decode(values[c])
+	}
+	return result
+}
+
+// search returns the first position of the key(or key prefix) >= key.
+func (x *dataIndex) search(key []interface{}) int {
+	return sort.Search(len(x.index), func(i int) bool {
+		return cmpValue(x.keyOf(x.index[i]), key) >= 0
+	})
+}
+
+// position returns the position of the true row index.
+func (x *dataIndex) position(trueIndex int) int {
+	for i := x.search(x.keyOf(trueIndex)); i < len(x.index); i++ {
+		if x.index[i] == trueIndex {
+			return i
+		}
+	}
+	return -1
+}
+func (x *dataIndex) insert(trueIndex int) {
+	key := x.keyOf(trueIndex)
+	pos := sort.Search(len(x.index), func(i int) bool {
+		return cmpValue(x.keyOf(x.index[i]), key) > 0
+	})
+	x.index = append(x.index[:pos], append([]int{trueIndex}, x.index[pos:]...)...)
+}
+func (x *dataIndex) remove(trueIndex int) {
+	if pos := x.position(trueIndex); pos > -1 {
+		x.index = append(x.index[:pos], x.index[pos+1:]...)
+	}
+}
+
+// rename replaces the true row index,used when the last row moved.
+func (x *dataIndex) rename(oldTrueIndex, newTrueIndex int) {
+	if pos := x.position(oldTrueIndex); pos > -1 {
+		x.index[pos] = newTrueIndex
+	}
+}
+func (x *dataIndex) rebuild() {
+	x.index = make([]int, x.dataTable.currentRows.Count())
+	keys := make([][]interface{}, len(x.index))
+	for i := range x.index {
+		x.index[i] = i
+		keys[i] = x.keyOf(i)
+	}
+	sort.SliceStable(x.index, func(i, j int) bool {
+		return cmpValue(keys[x.index[i]], keys[x.index[j]]) < 0
+	})
+}
+
+// conflict checks the key of unique index exists in other row than the
+// except true row index, a key with null never conflict.
+func (x *dataIndex) conflict(key []interface{}, except int) bool {
+	return x.unique && x.contains(key, except)
+}
+
+// contains checks the key exists in other row than the except true row
+// index, a key with null is never contained.
+func (x *dataIndex) contains(key []interface{}, except int) bool {
+	for _, v := range key {
+		if v == nil {
+			return false
+		}
+	}
+	for i := x.search(key); i < len(x.index) && cmpValue(x.keyOf(x.index[i]), key) == 0; i++ {
+		if x.index[i] != except {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *DataTable) indexByName(name string) *dataIndex {
+	for _, x := range d.indexes {
+		if x.name == name {
+			return x
+		}
+	}
+	return nil
+}
+
+// CreateIndex creates a secondary index on the columns, it is maintained
+// when the rows changed. A unique index rejects the duplicate key with an
+// error wraps KeyValueExists, the key has null is not duplicate.
+func (d *DataTable) CreateIndex(name string, columns []string, unique bool) error {
+	if d.indexByName(name) != nil {
+		return IndexExistsError
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("the index [%s] has no column", name)
+	}
+	x := &dataIndex{dataTable: d, name: name, unique: unique, columns: make([]int, len(columns))}
+	for i, c := range columns {
+		if x.columns[i] = d.ColumnIndex(c); x.columns[i] == -1 {
+			return ColumnNotFoundError(c)
+		}
+	}
+	x.rebuild()
+	if unique {
+		for i := 1; i < len(x.index); i++ {
+			if key := x.keyOf(x.index[i]); x.conflict(key, x.index[i]) {
+				return IndexKeyExistsError(name, key)
+			}
+		}
+	}
+	d.indexes = append(d.indexes, x)
+	return nil
+}
+
+// DropIndex removes the secondary index.
+func (d *DataTable) DropIndex(name string) error {
+	for i, x := range d.indexes {
+		if x.name == name {
+			d.indexes = append(d.indexes[:i], d.indexes[i+1:]...)
+			return nil
+		}
+	}
+	return IndexNotFoundError(name)
+}
+
+// IndexNames returns the names of the secondary indexes.
+func (d *DataTable) IndexNames() []string {
+	result := make([]string, len(d.indexes))
+	for i, x := range d.indexes {
+		result[i] = x.name
+	}
+	return result
+}
+
+// FindBy returns the index of the first row whose key of the index equals
+// the values, -1 if not found or the index not exists.
+func (d *DataTable) FindBy(indexName string, values ...interface{}) int {
+	x := d.indexByName(indexName)
+	if x == nil {
+		return -1
+	}
+	i := x.search(values)
+	if i < len(x.index) && cmpValue(x.keyOf(x.index[i]), values) == 0 {
+		return d.rowIndexOf(x.index[i])
+	}
+	return -1
+}
+
+// rowIndexes returns the row indexes of the rows whose key equals the key.
+func (x *dataIndex) rowIndexes(key []interface{}) []int {
+	result := []int{}
+	for i := x.search(key); i < len(x.index) && cmpValue(x.keyOf(x.index[i]), key) == 0; i++ {
+		result = append(result, x.dataTable.rowIndexOf(x.index[i]))
+	}
+	return result
+}
+
+// SearchBy returns the rows whose key of the index starts with the prefix,
+// nil if the index not exists.
+func (d *DataTable) SearchBy(indexName string, prefix ...interface{}) []map[string]interface{} {
+	x := d.indexByName(indexName)
+	if x == nil {
+		return nil
+	}
+	var result []map[string]interface{}
+	for i := x.search(prefix); i < len(x.index); i++ {
+		key := x.keyOf(x.index[i])
+		if len(key) < len(prefix) || cmpValue(key[:len(prefix)], prefix) != 0 {
+			break
+		}
+		result = append(result, d.Row(d.rowIndexOf(x.index[i])))
+	}
+	return result
+}
+
+// rowIndexOf returns the row index of the true row index, the primary
+// key index is searched by the key of the row.
+func (d *DataTable) rowIndexOf(trueIndex int) int {
+	if len(d.PK) == 0 {
+		return trueIndex
+	}
+	key := make([]interface{}, len(d.PK))
+	for i, c := range d.PK {
+		col := d.ColumnIndex(c)
+		key[i] = d.Columns[col].Decode(d.currentRows.Get(col, trueIndex))
+	}
+	if i := d.primaryIndexes.Search(key); i < len(d.primaryIndexes.index) && d.primaryIndexes.index[i] == trueIndex {
+		return i
+	}
+	//the primary key index is being changed
+	for i, v := range d.primaryIndexes.index {
+		if v == trueIndex {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkIndexes checks the stored row values against the unique indexes,
+// except is the true index of the row being changed,-1 for new row.
+func (d *DataTable) checkIndexes(values []interface{}, except int) error {
+	for _, x := range d.indexes {
+		if key := x.keyOfStore(values); x.conflict(key, except) {
+			return IndexKeyExistsError(x.name, key)
+		}
+	}
+	return nil
+}
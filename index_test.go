@@ -0,0 +1,93 @@
+package datatable
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCreateIndex(t *testing.T) {
+	table := CreateTestData()
+	if err := table.CreateIndex("ix3", []string{"column3", "column2"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.CreateIndex("ix3", []string{"column3"}, false); err != IndexExistsError {
+		t.Error("error", err)
+	}
+	if err := table.CreateIndex("ux3", []string{"column3"}, true); !errors.Is(err, KeyValueExists) {
+		t.Error("error", err)
+	}
+	if rows := table.SearchBy("ix3", "test1"); len(rows) != 4 {
+		t.Error("error", len(rows))
+	}
+	i := table.FindBy("ix3", "test", int64(1))
+	if i == -1 || table.Row(i)["column1"] != "second" {
+		t.Error("error", i)
+	}
+	if table.FindBy("ix3", "test", int64(2)) != -1 {
+		t.Error("error")
+	}
+
+	if err := table.SetValues(i, "second", int64(1), "changed"); err != nil {
+		t.Fatal(err)
+	}
+	if table.FindBy("ix3", "test", int64(1)) != -1 || table.FindBy("ix3", "changed", int64(1)) != i {
+		t.Error("error")
+	}
+	table.AddValues("new", int64(5), "test1")
+	if rows := table.SearchBy("ix3", "test1"); len(rows) != 5 || rows[0]["column1"] != "new" {
+		t.Error("error", rows)
+	}
+	table.DeleteRow(table.Find("aa,\"'`a", int64(10)))
+	table.DeleteRow(table.Find("bbb", int64(10)))
+	if rows := table.SearchBy("ix3", "test1"); len(rows) != 3 {
+		t.Error("error", len(rows))
+	}
+	for i := 0; i < table.RowCount(); i++ {
+		r := table.Row(i)
+		if table.FindBy("ix3", r["column3"], r["column2"]) == -1 {
+			t.Error("not found", r)
+		}
+	}
+	table.Clear()
+	if table.FindBy("ix3", "changed", int64(1)) != -1 {
+		t.Error("error")
+	}
+}
+func TestUniqueIndex(t *testing.T) {
+	table := NewDataTable("table1")
+	table.AddColumn(NewInt64Column("id"))
+	table.AddColumn(StringColumn("code", 0, false))
+	table.SetPK("id")
+	if err := table.CreateIndex("ux_code", []string{"code"}, true); err != nil {
+		t.Fatal(err)
+	}
+	table.AddValues(int64(1), "a")
+	table.AddValues(int64(2), "b")
+	if err := table.AddValues(int64(3), "a"); !errors.Is(err, KeyValueExists) {
+		t.Error("error", err)
+	}
+	if err := table.AddValues(int64(3), nil); err != nil {
+		t.Error(err)
+	}
+	if err := table.AddValues(int64(4), nil); err != nil {
+		t.Error(err)
+	}
+	if err := table.SetValues(table.Find(int64(2)), int64(2), "a"); !errors.Is(err, KeyValueExists) {
+		t.Error("error", err)
+	}
+	if err := table.SetValues(table.Find(int64(2)), int64(20), "b"); err != nil {
+		t.Error(err)
+	}
+	if i := table.FindBy("ux_code", "b"); i == -1 || table.GetValue(i, 0) != int64(20) {
+		t.Error("error", i)
+	}
+	if err := table.DropIndex("ux_code"); err != nil {
+		t.Error(err)
+	}
+	if table.FindBy("ux_code", "b") != -1 || table.SearchBy("ux_code", "b") != nil {
+		t.Error("the dropped index must not be found")
+	}
+	if err := table.AddValues(int64(5), "a"); err != nil {
+		t.Error(err)
+	}
+}
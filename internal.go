@@ -3,6 +3,8 @@ package datatable
 import (
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -31,6 +33,17 @@ func safeToString(s interface{}) string {
 
 //0-equ -1 less 1 large
 func cmpValue(v1, v2 interface{}) int {
+	//null is less than any value
+	if v1 == nil || v2 == nil {
+		switch {
+		case v1 == v2:
+			return 0
+		case v1 == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
 	switch v1.(type) {
 	case []interface{}:
 		for i, e1 := range v1.([]interface{}) {
@@ -270,3 +283,27 @@ func cmpByteaSlice(v1, v2 [][]byte) int {
 	}
 	return -1
 }
+
+// hashKey returns a string key of the values, the equal values
+// (cmpValue is 0) have the same key.
+func hashKey(values []interface{}) string {
+	var sb strings.Builder
+	for _, v := range values {
+		var s string
+		switch tv := v.(type) {
+		case nil:
+			sb.WriteString("n;")
+			continue
+		case time.Time:
+			s = strconv.FormatInt(tv.UnixNano(), 10)
+		case []byte:
+			s = string(tv)
+		default:
+			s = fmt.Sprint(tv)
+		}
+		sb.WriteString(strconv.Itoa(len(s)))
+		sb.WriteByte(':')
+		sb.WriteString(s)
+	}
+	return sb.String()
+}
@@ -0,0 +1,196 @@
+package datatable
+
+import (
+	"fmt"
+)
+
+type joinKind int
+
+const (
+	innerJoin joinKind = iota
+	leftJoin
+	rightJoin
+	fullJoin
+)
+
+// JoinColumn is a pair of the left and right column to join on.
+type JoinColumn struct {
+	Left  string
+	Right string
+}
+
+// JoinOptions rename the right columns whose name clash with a left
+// column, the new name is Prefix + name + Suffix. When both are empty the
+// prefix is the right table name and "_".
+type JoinOptions struct {
+	Prefix string
+	Suffix string
+}
+
+// InnerJoin returns a new table with the left and right columns, holding
+// the pairs of rows that the on columns are equal. Null never equal.
+func (d *DataTable) InnerJoin(right *DataTable, on []JoinColumn, opts *JoinOptions) (*DataTable, error) {
+	return d.join(innerJoin, right, on, opts)
+}
+
+// LeftJoin is InnerJoin plus the left rows without matched, the right
+// columns of the result are nullable.
+func (d *DataTable) LeftJoin(right *DataTable, on []JoinColumn, opts *JoinOptions) (*DataTable, error) {
+	return d.join(leftJoin, right, on, opts)
+}
+
+// RightJoin is InnerJoin plus the right rows without matched, the left
+// columns of the result are nullable.
+func (d *DataTable) RightJoin(right *DataTable, on []JoinColumn, opts *JoinOptions) (*DataTable, error) {
+	return d.join(rightJoin, right, on, opts)
+}
+
+// FullJoin is InnerJoin plus the left and right rows without matched, all
+// columns of the result are nullable.
+func (d *DataTable) FullJoin(right *DataTable, on []JoinColumn, opts *JoinOptions) (*DataTable, error) {
+	return d.join(fullJoin, right, on, opts)
+}
+
+// joinProbe finds the right rows matching the key of a left row.
+type joinProbe func(key []interface{}) []int
+
+func (d *DataTable) join(kind joinKind, right *DataTable, on []JoinColumn, opts *JoinOptions) (*DataTable, error) {
+	if len(on) == 0 {
+		return nil, fmt.Errorf("the join columns is empty")
+	}
+	leftIdx := make([]int, len(on))
+	rightIdx := make([]int, len(on))
+	for i, c := range on {
+		if leftIdx[i] = d.ColumnIndex(c.Left); leftIdx[i] == -1 {
+			return nil, ColumnNotFoundError(c.Left)
+		}
+		if rightIdx[i] = right.ColumnIndex(c.Right); rightIdx[i] == -1 {
+			return nil, ColumnNotFoundError(c.Right)
+		}
+		if lt, rt := d.Columns[leftIdx[i]].DataType, right.Columns[rightIdx[i]].DataType; lt != rt {
+			return nil, fmt.Errorf("the join column %s(%s) and %s(%s) type mismatch", c.Left, lt, c.Right, rt)
+		}
+	}
+	result, err := d.joinSchema(kind, right, opts)
+	if err != nil {
+		return nil, err
+	}
+	probe := right.joinProbe(rightIdx)
+	var matched []bool
+	if kind == rightJoin || kind == fullJoin {
+		matched = make([]bool, right.RowCount())
+	}
+	rightNulls := make([]interface{}, right.ColumnCount())
+	for i := 0; i < d.RowCount(); i++ {
+		leftValues := d.GetValues(i)
+		rows := probe(pickValues(leftValues, leftIdx))
+		for _, j := range rows {
+			if matched != nil {
+				matched[j] = true
+			}
+			if err := result.addValues(append(leftValues, right.GetValues(j)...)); err != nil {
+				return nil, err
+			}
+		}
+		if len(rows) == 0 && (kind == leftJoin || kind == fullJoin) {
+			if err := result.addValues(append(leftValues, rightNulls...)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	leftNulls := make([]interface{}, d.ColumnCount())
+	for j, ok := range matched {
+		if !ok {
+			if err := result.addValues(append(leftNulls, right.GetValues(j)...)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	result.AcceptChange()
+	return result, nil
+}
+
+// joinSchema returns the empty result table of the join.
+func (d *DataTable) joinSchema(kind joinKind, right *DataTable, opts *JoinOptions) (*DataTable, error) {
+	prefix, suffix := right.TableName+"_", ""
+	if opts != nil && (opts.Prefix != "" || opts.Suffix != "") {
+		prefix, suffix = opts.Prefix, opts.Suffix
+	}
+	result := NewDataTable(d.TableName)
+	for _, c := range d.Columns {
+		nc := c.Clone()
+		if kind == rightJoin || kind == fullJoin {
+			nc.NotNull = false
+		}
+		result.AddColumn(nc)
+	}
+	for _, c := range right.Columns {
+		nc := c.Clone()
+		if d.ColumnIndex(nc.Name) > -1 {
+			nc.Name = prefix + nc.Name + suffix
+		}
+		if result.ColumnIndex(nc.Name) > -1 {
+			return nil, fmt.Errorf("the column %s of join result exists", nc.Name)
+		}
+		if kind == leftJoin || kind == fullJoin {
+			nc.NotNull = false
+		}
+		result.AddColumn(nc)
+	}
+	return result, nil
+}
+
+// joinProbe returns the probe of the columns, uses Find when the columns
+// are the primary key, otherwise builds a hash of the rows.
+func (d *DataTable) joinProbe(columns []int) joinProbe {
+	if len(d.PK) == len(columns) {
+		//the key position of every primary key column
+		pos := make([]int, len(d.PK))
+		for i, pk := range d.PK {
+			pos[i] = -1
+			for j, c := range columns {
+				if d.Columns[c].Name == pk {
+					pos[i] = j
+				}
+			}
+			if pos[i] == -1 {
+				pos = nil
+				break
+			}
+		}
+		if pos != nil {
+			return func(key []interface{}) []int {
+				for _, v := range key {
+					if v == nil {
+						return nil
+					}
+				}
+				if i := d.Find(pickValues(key, pos)...); i > -1 {
+					return []int{i}
+				}
+				return nil
+			}
+		}
+	}
+	hash := map[string][]int{}
+	for i := 0; i < d.RowCount(); i++ {
+		key := make([]interface{}, len(columns))
+		hasNull := false
+		for j, c := range columns {
+			key[j] = d.GetValue(i, c)
+			hasNull = hasNull || key[j] == nil
+		}
+		if !hasNull {
+			k := hashKey(key)
+			hash[k] = append(hash[k], i)
+		}
+	}
+	return func(key []interface{}) []int {
+		for _, v := range key {
+			if v == nil {
+				return nil
+			}
+		}
+		return hash[hashKey(key)]
+	}
+}
@@ -0,0 +1,98 @@
+package datatable
+
+import (
+	"reflect"
+	"testing"
+)
+
+func createJoinData() (*DataTable, *DataTable) {
+	orders := NewDataTable("orders")
+	orders.AddColumn(NewInt64Column("id"))
+	orders.AddColumn(StringColumn("customer", 0, false))
+	orders.AddColumn(NewFloat64Column("amount"))
+	orders.SetPK("id")
+	orders.AddValues(int64(1), "c1", 10.0)
+	orders.AddValues(int64(2), "c2", 20.0)
+	orders.AddValues(int64(3), "c9", 30.0)
+	orders.AddValues(int64(4), nil, 40.0)
+
+	customers := NewDataTable("customers")
+	customers.AddColumn(NewStringColumn("id"))
+	customers.AddColumn(NewStringColumn("name"))
+	customers.SetPK("id")
+	customers.AddValues("c1", "one")
+	customers.AddValues("c2", "two")
+	customers.AddValues("c3", "three")
+	return orders, customers
+}
+func TestJoin(t *testing.T) {
+	orders, customers := createJoinData()
+	on := []JoinColumn{{Left: "customer", Right: "id"}}
+	cases := []struct {
+		join func(*DataTable, []JoinColumn, *JoinOptions) (*DataTable, error)
+		rows int
+	}{
+		{orders.InnerJoin, 2},
+		{orders.LeftJoin, 4},
+		{orders.RightJoin, 3},
+		{orders.FullJoin, 5},
+	}
+	for i, c := range cases {
+		result, err := c.join(customers, on, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.RowCount() != c.rows {
+			t.Error(i, result.RowCount())
+		}
+		if !reflect.DeepEqual(result.ColumnNames(), []string{"id", "customer", "amount", "customers_id", "name"}) {
+			t.Error(result.ColumnNames())
+		}
+	}
+	result, err := orders.LeftJoin(customers, on, &JoinOptions{Suffix: "_r"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Columns[3].Name != "id_r" || result.Columns[3].NotNull || !result.Columns[0].NotNull {
+		t.Error("error")
+	}
+	if r := result.Row(2); r["id"] != int64(3) || r["name"] != nil {
+		t.Error(r)
+	}
+	result, _ = orders.RightJoin(customers, on, nil)
+	if r := result.Row(2); r["id"] != nil || r["name"] != "three" || result.Columns[0].NotNull {
+		t.Error(r)
+	}
+	//no primary key,use the hash
+	customers.SetPK()
+	customers.AddValues("c1", "one again")
+	if result, _ = orders.InnerJoin(customers, on, nil); result.RowCount() != 3 {
+		t.Error("error", result.RowCount())
+	}
+	if _, err := orders.InnerJoin(customers, []JoinColumn{{Left: "amount", Right: "id"}}, nil); err == nil {
+		t.Error("must be error")
+	}
+}
+
+func TestJoinKeepsNulls(t *testing.T) {
+	orders, customers := createJoinData()
+	//the defaults of the sources don't fill the nulls of the result
+	orders.Columns[1].DefaultValue = "c0"
+	customers.Columns[1].DefaultValue = "none"
+	result, err := orders.FullJoin(customers, []JoinColumn{{Left: "customer", Right: "id"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer, name := result.ColumnIndex("customer"), result.ColumnIndex("name")
+	nulls := map[int]int{}
+	for i := 0; i < result.RowCount(); i++ {
+		for _, col := range []int{customer, name} {
+			if result.GetValue(i, col) == nil {
+				nulls[col]++
+			}
+		}
+	}
+	if result.RowCount() != 5 || nulls[customer] != 2 || nulls[name] != 2 {
+		t.Error(result.AsCsv())
+	}
+}
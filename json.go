@@ -0,0 +1,225 @@
+package datatable
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// jsonTable is the JSON format of DataTable, the row values are in the
+// order of columns, bytea is base64 and time is RFC3339Nano.
+type jsonTable struct {
+	TableName string
+	Columns   []jsonColumn
+	PK        []string
+	Rows      [][]json.RawMessage
+	//the changes,only with MarshalJSONWithChanges
+	RowStatus  []int               `json:",omitempty"`
+	OriginData [][]json.RawMessage `json:",omitempty"`
+	DeleteRows [][]json.RawMessage `json:",omitempty"`
+}
+type jsonColumn struct {
+	Name              string
+	DataType          ColumnType
+	MaxSize           int
+	NotNull           bool
+	DefaultValue      json.RawMessage `json:",omitempty"`
+	AutoIncrement     bool            `json:",omitempty"`
+	AutoIncrementSeed int64           `json:",omitempty"`
+	AutoIncrementStep int64           `json:",omitempty"`
+	Expression        string          `json:",omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler, writes the schema and the
+// rows without the changes.
+func (d *DataTable) MarshalJSON() ([]byte, error) {
+	return d.marshalJSON(false)
+}
+
+// MarshalJSONWithChanges is MarshalJSON plus the status and origin data
+// of every row and the deleted rows, so the changes can be got by
+// GetChange after UnmarshalJSON.
+func (d *DataTable) MarshalJSONWithChanges() ([]byte, error) {
+	return d.marshalJSON(true)
+}
+func (d *DataTable) marshalJSON(withChanges bool) ([]byte, error) {
+	t := jsonTable{
+		TableName: d.TableName,
+		Columns:   make([]jsonColumn, d.ColumnCount()),
+		PK:        d.PK,
+		Rows:      make([][]json.RawMessage, d.RowCount()),
+	}
+	var err error
+	for i, c := range d.Columns {
+		t.Columns[i] = jsonColumn{Name: c.Name, DataType: c.DataType, MaxSize: c.MaxSize, NotNull: c.NotNull,
+			AutoIncrement: c.AutoIncrement, AutoIncrementSeed: c.AutoIncrementSeed, AutoIncrementStep: c.AutoIncrementStep,
+			Expression: c.Expression}
+		if c.DefaultValue != nil {
+			if err := c.Valid(c.DefaultValue); err != nil {
+				return nil, fmt.Errorf("the default value: %v", err)
+			}
+			if t.Columns[i].DefaultValue, err = json.Marshal(c.Decode(c.Encode(c.DefaultValue))); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for i := range t.Rows {
+		if t.Rows[i], err = marshalJSONValues(d.GetValues(i)); err != nil {
+			return nil, err
+		}
+	}
+	if withChanges {
+		t.RowStatus = make([]int, d.RowCount())
+		t.OriginData = make([][]json.RawMessage, d.RowCount())
+		for i := range t.RowStatus {
+			trueIndex := d.primaryIndexes.trueIndex(i)
+			t.RowStatus[i] = int(d.rowStatus[trueIndex])
+			if d.rowStatus[trueIndex] == UPDATE {
+				if t.OriginData[i], err = marshalJSONValues(d.decodeValues(d.originData[trueIndex])); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for i := 0; i < d.deleteRows.Count(); i++ {
+			vals, err := marshalJSONValues(d.decodeValues(d.deleteRows.GetRow(i)))
+			if err != nil {
+				return nil, err
+			}
+			t.DeleteRows = append(t.DeleteRows, vals)
+		}
+	}
+	return json.Marshal(t)
+}
+func marshalJSONValues(values []interface{}) ([]json.RawMessage, error) {
+	result := make([]json.RawMessage, len(values))
+	for i, v := range values {
+		bys, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = bys
+	}
+	return result, nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler, replaces the table with
+// the JSON written by MarshalJSON or MarshalJSONWithChanges. Without the
+// changes, all rows are unchanged.
+func (d *DataTable) UnmarshalJSON(data []byte) error {
+	var t jsonTable
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+	result := NewDataTable(t.TableName)
+	for _, c := range t.Columns {
+		if _, ok := reflectType[c.DataType]; !ok {
+			return fmt.Errorf("column %q: invalid data type %q", c.Name, c.DataType)
+		}
+		if result.ColumnIndex(c.Name) > -1 {
+			return fmt.Errorf("column %q: %v", c.Name, ColumnExistsError)
+		}
+		col := NewDataColumn(c.Name, c.DataType, c.MaxSize, c.NotNull)
+		col.AutoIncrement, col.AutoIncrementSeed, col.AutoIncrementStep = c.AutoIncrement, c.AutoIncrementSeed, c.AutoIncrementStep
+		if c.DefaultValue != nil {
+			v, err := unmarshalJSONValue(col, c.DefaultValue)
+			if err != nil {
+				return fmt.Errorf("the default value: %v", err)
+			}
+			col.DefaultValue = v
+		}
+		if col.Expression = c.Expression; col.IsComputed() {
+			if err := col.compileExpression(result); err != nil {
+				return err
+			}
+		}
+		result.AddColumn(col)
+	}
+	for _, c := range t.PK {
+		if result.ColumnIndex(c) == -1 {
+			return ColumnNotFoundError(c)
+		}
+	}
+	result.SetPK(t.PK...)
+	for i, raw := range t.Rows {
+		values, err := result.unmarshalJSONValues(raw)
+		if err != nil {
+			return fmt.Errorf("row %d: %v", i, err)
+		}
+		//the null values are not filled by the defaults
+		if err := result.addValues(values); err != nil {
+			return fmt.Errorf("row %d: %v", i, err)
+		}
+	}
+	result.AcceptChange()
+	if t.RowStatus != nil {
+		if len(t.RowStatus) != len(t.Rows) {
+			return fmt.Errorf("the count of row status %d not equal rows %d", len(t.RowStatus), len(t.Rows))
+		}
+		//the rows be added in order,so the true index is the position
+		for i, s := range t.RowStatus {
+			status := byte(s)
+			switch {
+			case s < 0 || s > int(INSERT):
+				return fmt.Errorf("row %d: invalid row status %d", i, s)
+			case status == UPDATE:
+				if i >= len(t.OriginData) || t.OriginData[i] == nil {
+					return fmt.Errorf("row %d: the updated row has no origin data", i)
+				}
+				values, err := result.unmarshalJSONValues(t.OriginData[i])
+				if err != nil {
+					return fmt.Errorf("origin data of row %d: %v", i, err)
+				}
+				result.originData[i] = result.encodeValues(values)
+			}
+			result.setRowStatus(i, status)
+			result.changed = result.changed || status != UNCHANGE
+		}
+	}
+	for i, raw := range t.DeleteRows {
+		values, err := result.unmarshalJSONValues(raw)
+		if err != nil {
+			return fmt.Errorf("delete row %d: %v", i, err)
+		}
+		result.deleteRows.AddRow(result.encodeValues(values))
+		result.changed = true
+		//the keys of the deleted rows are not used again
+		for j, c := range result.Columns {
+			if v, ok := values[j].(int64); ok && c.AutoIncrement {
+				c.seeAutoIncrement(v)
+			}
+		}
+	}
+	return d.replaceWith(result)
+}
+
+// unmarshalJSONValues decodes the values by the column type and checks
+// them.
+func (d *DataTable) unmarshalJSONValues(raw []json.RawMessage) ([]interface{}, error) {
+	if len(raw) != d.ColumnCount() {
+		return nil, NumberOfValueError(len(raw), d.ColumnCount())
+	}
+	result := make([]interface{}, len(raw))
+	for i, c := range d.Columns {
+		v, err := unmarshalJSONValue(c, raw[i])
+		if err != nil {
+			return nil, err
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+// unmarshalJSONValue decodes the value by the column type and checks it.
+func unmarshalJSONValue(c *DataColumn, raw json.RawMessage) (interface{}, error) {
+	if string(raw) == "null" {
+		return nil, nil
+	}
+	v := reflect.New(c.ReflectType())
+	if err := json.Unmarshal(raw, v.Interface()); err != nil {
+		return nil, fmt.Errorf("column %q: %v", c.Name, err)
+	}
+	if err := c.Valid(v.Elem().Interface()); err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface(), nil
+}
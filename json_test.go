@@ -0,0 +1,76 @@
+package datatable
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSON(t *testing.T) {
+	src := CreateTestData()
+	src.AddColumn(TimeColumn("t", false))
+	src.AddColumn(ByteaColumn("b", false))
+	t1 := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	src.SetValues(0, append(src.GetValues(0)[:3], t1, []byte{1, 2})...)
+	src.AcceptChange()
+	src.SetValues(1, append(src.GetValues(1)[:2], "changed", t1, nil)...)
+	src.DeleteRow(2)
+	src.AddValues("new", int64(1), "test", nil, []byte{3})
+
+	bys, err := json.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(bys), `"2020-01-02T03:04:05.000000006Z","AQI="`) || strings.Contains(string(bys), "RowStatus") {
+		t.Error(string(bys))
+	}
+	table := NewDataTable("")
+	if err := json.Unmarshal(bys, table); err != nil {
+		t.Fatal(err)
+	}
+	if table.TableName != src.TableName || !reflect.DeepEqual(table.PK, src.PK) ||
+		table.AsCsv() != src.AsCsv() || table.HasChange() || table.GetChange().RowCount != 0 {
+		t.Error(table.AsCsv())
+	}
+	for i, c := range src.Columns {
+		if c.Name != table.Columns[i].Name || c.DataType != table.Columns[i].DataType || c.NotNull != table.Columns[i].NotNull {
+			t.Error(table.Columns[i])
+		}
+	}
+	//with changes
+	if bys, err = src.MarshalJSONWithChanges(); err != nil {
+		t.Fatal(err)
+	}
+	table = NewDataTable("")
+	if err := json.Unmarshal(bys, table); err != nil {
+		t.Fatal(err)
+	}
+	if table.AsCsv() != src.AsCsv() || !table.HasChange() {
+		t.Error(table.AsCsv())
+	}
+	if !reflect.DeepEqual(table.GetChange(), src.GetChange()) {
+		t.Error(table.GetChange())
+	}
+	if i := table.Find("new", int64(1)); i == -1 {
+		t.Error("not found")
+	}
+	//errors
+	for _, s := range []string{
+		`{"Columns":[{"Name":"a","DataType":"nothing"}]}`,
+		`{"Columns":[{"Name":"a","DataType":"int64"}],"PK":["b"]}`,
+		`{"Columns":[{"Name":"a","DataType":"int64"}],"Rows":[["x"]]}`,
+		`{"Columns":[{"Name":"a","DataType":"int64"}],"Rows":[[1,2]]}`,
+		`{"Columns":[{"Name":"a","DataType":"int64","NotNull":true}],"Rows":[[null]]}`,
+		`{"Columns":[{"Name":"a","DataType":"int64"}],"Rows":[[1]],"RowStatus":[1]}`,
+		`{"Columns":[{"Name":"a","DataType":"int64"}],"Rows":[[1]],"RowStatus":[3]}`,
+	} {
+		if err := json.Unmarshal([]byte(s), table); err == nil {
+			t.Error("must be error", s)
+		}
+	}
+	if table.AsCsv() != src.AsCsv() {
+		t.Error("the table changed by error")
+	}
+}
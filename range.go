@@ -0,0 +1,109 @@
+package datatable
+
+import (
+	"sort"
+)
+
+// RangeIterator iterates the rows of a primary key range, in ascending or
+// descending order:
+//
+//	it := table.RangeIter(lo, hi, true, false, false)
+//	for it.Next() {
+//		row := it.Row()
+//	}
+type RangeIterator struct {
+	table      *DataTable
+	start, end int
+	desc       bool
+	pos        int
+}
+
+// cmpKeyPrefix compares the first len(bound) values of the key with bound.
+func cmpKeyPrefix(key, bound []interface{}) int {
+	if len(bound) < len(key) {
+		key = key[:len(bound)]
+	}
+	return cmpValue(key, bound)
+}
+
+// rangeBounds returns the row index range [start,end) of the primary key
+// between lo and hi, a nil or empty bound is open. The bound can be a
+// prefix of the primary key.
+func (d *DataTable) rangeBounds(lo, hi []interface{}, loInclusive, hiInclusive bool) (int, int) {
+	n := d.primaryIndexes.Len()
+	if len(d.PK) == 0 {
+		return 0, 0
+	}
+	start, end := 0, n
+	if len(lo) > 0 {
+		start = sort.Search(n, func(i int) bool {
+			c := cmpKeyPrefix(d.KeyValues(i), lo)
+			return c > 0 || c == 0 && loInclusive
+		})
+	}
+	if len(hi) > 0 {
+		end = sort.Search(n, func(i int) bool {
+			c := cmpKeyPrefix(d.KeyValues(i), hi)
+			return c > 0 || c == 0 && !hiInclusive
+		})
+	}
+	if end < start {
+		end = start
+	}
+	return start, end
+}
+
+// RangeIter returns an iterator of the rows whose primary key is between
+// lo and hi, a nil bound is open and a bound can be a prefix of the key,
+// such as the customer of a (customer, time) key. The table without
+// primary key has no row in any range. The table must not be changed
+// while iterating.
+func (d *DataTable) RangeIter(lo, hi []interface{}, loInclusive, hiInclusive, desc bool) *RangeIterator {
+	start, end := d.rangeBounds(lo, hi, loInclusive, hiInclusive)
+	it := &RangeIterator{table: d, start: start, end: end, desc: desc, pos: start - 1}
+	if desc {
+		it.pos = end
+	}
+	return it
+}
+
+// Next moves to the next row, returns false when there is no more row.
+func (it *RangeIterator) Next() bool {
+	if it.desc {
+		if it.pos > it.start {
+			it.pos--
+			return true
+		}
+		return false
+	}
+	if it.pos+1 < it.end {
+		it.pos++
+		return true
+	}
+	return false
+}
+
+// RowIndex returns the row index of current row.
+func (it *RangeIterator) RowIndex() int {
+	return it.pos
+}
+
+// Row returns the current row.
+func (it *RangeIterator) Row() map[string]interface{} {
+	return it.table.Row(it.pos)
+}
+
+// Len returns the count of rows in the range.
+func (it *RangeIterator) Len() int {
+	return it.end - it.start
+}
+
+// Range returns the rows whose primary key is between lo and hi in
+// ascending order, see RangeIter.
+func (d *DataTable) Range(lo, hi []interface{}, loInclusive, hiInclusive bool) []map[string]interface{} {
+	var result []map[string]interface{}
+	for it := d.RangeIter(lo, hi, loInclusive, hiInclusive, false); it.Next(); {
+		result = append(result, it.Row())
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+package datatable
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRange(t *testing.T) {
+	table := NewDataTable("orders")
+	table.AddColumn(NewStringColumn("customer"))
+	table.AddColumn(NewTimeColumn("time"))
+	table.AddColumn(NewInt64Column("amount"))
+	table.SetPK("customer", "time")
+	t1 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		table.AddValues("x", t1.AddDate(0, 0, i), int64(i))
+		table.AddValues("y", t1.AddDate(0, 0, i), int64(10+i))
+	}
+	table.AddValues("a", t1, int64(100))
+	amounts := func(rows []map[string]interface{}) []int64 {
+		result := []int64{}
+		for _, r := range rows {
+			result = append(result, r["amount"].(int64))
+		}
+		return result
+	}
+	cases := []struct {
+		lo, hi       []interface{}
+		loInc, hiInc bool
+		want         []int64
+	}{
+		{[]interface{}{"x", t1.AddDate(0, 0, 1)}, []interface{}{"x", t1.AddDate(0, 0, 3)}, true, true, []int64{1, 2, 3}},
+		{[]interface{}{"x", t1.AddDate(0, 0, 1)}, []interface{}{"x", t1.AddDate(0, 0, 3)}, false, false, []int64{2}},
+		{[]interface{}{"x"}, []interface{}{"x"}, true, true, []int64{0, 1, 2, 3, 4}},
+		{[]interface{}{"x", t1.AddDate(0, 0, 3)}, nil, true, true, []int64{3, 4, 10, 11, 12, 13, 14}},
+		{nil, []interface{}{"x"}, true, false, []int64{100}},
+		{[]interface{}{"y"}, []interface{}{"x"}, true, true, []int64{}},
+	}
+	for i, c := range cases {
+		if got := amounts(table.Range(c.lo, c.hi, c.loInc, c.hiInc)); !reflect.DeepEqual(got, c.want) {
+			t.Error(i, got)
+		}
+	}
+	it := table.RangeIter([]interface{}{"y", t1.AddDate(0, 0, 2)}, nil, true, true, true)
+	if it.Len() != 3 {
+		t.Error("error", it.Len())
+	}
+	got := []int64{}
+	for it.Next() {
+		got = append(got, table.GetValue(it.RowIndex(), 2).(int64))
+	}
+	if !reflect.DeepEqual(got, []int64{14, 13, 12}) {
+		t.Error(got)
+	}
+}
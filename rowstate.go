@@ -0,0 +1,109 @@
+package datatable
+
+import "errors"
+
+// InvalidRowStateError is returned when the row state doesn't allow the
+// operation.
+var InvalidRowStateError = errors.New("the row state is invalid for the operation")
+
+// RowState returns the status of the row, UNCHANGE, UPDATE or INSERT.
+func (d *DataTable) RowState(rowIndex int) byte {
+	return d.rowStatus[d.primaryIndexes.trueIndex(rowIndex)]
+}
+
+// setRowStatus sets the status of the row at the true index, the count of
+// the changed rows is kept and the version is increased so the views
+// filtered by the row state are refreshed.
+func (d *DataTable) setRowStatus(trueIndex int, status byte) {
+	old := d.rowStatus[trueIndex]
+	if old == status {
+		return
+	}
+	switch {
+	case old == UNCHANGE:
+		d.changedRows++
+	case status == UNCHANGE:
+		d.changedRows--
+	}
+	d.rowStatus[trueIndex] = status
+	d.version++
+}
+
+// SetAdded marks the unchanged row as inserted.
+func (d *DataTable) SetAdded(rowIndex int) error {
+	if rowIndex < 0 || rowIndex >= d.RowCount() {
+		return RowNotFoundError
+	}
+	trueIndex := d.primaryIndexes.trueIndex(rowIndex)
+	if d.rowStatus[trueIndex] != UNCHANGE {
+		return InvalidRowStateError
+	}
+	d.setRowStatus(trueIndex, INSERT)
+	d.changed = true
+	d.version++
+	d.history.clear()
+	return nil
+}
+
+// SetModified marks the unchanged row as updated without changing the
+// values, the origin data is the current values.
+func (d *DataTable) SetModified(rowIndex int) error {
+	if rowIndex < 0 || rowIndex >= d.RowCount() {
+		return RowNotFoundError
+	}
+	trueIndex := d.primaryIndexes.trueIndex(rowIndex)
+	if d.rowStatus[trueIndex] != UNCHANGE {
+		return InvalidRowStateError
+	}
+	d.setRowStatus(trueIndex, UPDATE)
+	d.originData[trueIndex] = d.currentRows.GetRow(trueIndex)
+	d.changed = true
+	d.version++
+	d.history.clear()
+	return nil
+}
+
+// AcceptRowChange accepts the change of the row, the row becomes
+// unchanged.
+func (d *DataTable) AcceptRowChange(rowIndex int) error {
+	if rowIndex < 0 || rowIndex >= d.RowCount() {
+		return RowNotFoundError
+	}
+	trueIndex := d.primaryIndexes.trueIndex(rowIndex)
+	d.setRowStatus(trueIndex, UNCHANGE)
+	d.originData[trueIndex] = nil
+	d.updateChanged()
+	d.version++
+	d.history.clear()
+	return nil
+}
+
+// DeletedRowCount returns the count of the deleted rows.
+func (d *DataTable) DeletedRowCount() int {
+	return d.deleteRows.Count()
+}
+
+// DeletedRow returns the origin values of the i-th deleted row.
+func (d *DataTable) DeletedRow(i int) map[string]interface{} {
+	result := map[string]interface{}{}
+	for j, v := range d.deleteRows.GetRow(i) {
+		result[d.Columns[j].Name] = d.Columns[j].Decode(v)
+	}
+	return result
+}
+
+// RestoreDeletedRow adds the i-th deleted row back with the origin
+// values as an unchanged row, returns the new row index.
+func (d *DataTable) RestoreDeletedRow(i int) (int, error) {
+	if i < 0 || i >= d.deleteRows.Count() {
+		return -1, RowNotFoundError
+	}
+	if err := d.addRowValues(d.decodeValues(d.deleteRows.GetRow(i))); err != nil {
+		return -1, err
+	}
+	d.setRowStatus(len(d.rowStatus)-1, UNCHANGE)
+	d.deleteRows.Delete(i)
+	d.updateChanged()
+	d.history.clear()
+	return d.rowIndexOf(d.currentRows.Count() - 1), nil
+}
@@ -0,0 +1,64 @@
+package datatable
+
+import (
+	"testing"
+)
+
+func TestRowState(t *testing.T) {
+	table := CreateTestData()
+	table.AcceptChange()
+	i := table.Find("bbb", int64(10))
+	if err := table.SetModified(i); err != nil || table.RowState(i) != UPDATE || !table.HasChange() {
+		t.Error(err)
+	}
+	chg := table.GetChange()
+	if len(chg.UpdateRows) != 1 || chg.UpdateRows[0].OriginData[0] != "bbb" {
+		t.Error(chg)
+	}
+	if err := table.SetAdded(i); err != InvalidRowStateError {
+		t.Error(err)
+	}
+	if err := table.AcceptRowChange(i); err != nil || table.RowState(i) != UNCHANGE || table.HasChange() {
+		t.Error(err)
+	}
+	if err := table.SetAdded(i); err != nil || table.RowState(i) != INSERT || len(table.GetChange().InsertRows) != 1 {
+		t.Error(err)
+	}
+	table.AcceptRowChange(i)
+	//the deleted rows
+	table.SetValues(table.Find("first", int64(10)), "first", int64(10), "changed")
+	table.DeleteRow(table.Find("first", int64(10)))
+	table.DeleteRow(table.Find("second", int64(1)))
+	if table.DeletedRowCount() != 2 || table.DeletedRow(0)["column3"] != "test1" || table.DeletedRow(1)["column1"] != "second" {
+		t.Error("error", table.DeletedRow(0))
+	}
+	j, err := table.RestoreDeletedRow(0)
+	if err != nil || table.RowState(j) != UNCHANGE || table.GetValue(j, 2) != "test1" {
+		t.Error(err)
+	}
+	if table.DeletedRowCount() != 1 || table.DeletedRow(0)["column1"] != "second" {
+		t.Error("error")
+	}
+	table.AddValues("second", int64(1), "new")
+	if _, err := table.RestoreDeletedRow(0); err != KeyValueExists {
+		t.Error(err)
+	}
+	if _, err := table.RestoreDeletedRow(1); err != RowNotFoundError {
+		t.Error(err)
+	}
+}
+
+func TestRestoreDeletedRowKeepsNulls(t *testing.T) {
+	table := NewDataTable("items")
+	table.AddColumn(NewInt64Column("id"))
+	table.AddColumn(StringColumn("code", 0, false))
+	table.SetPK("id")
+	table.AddValues(int64(1), nil)
+	table.AcceptChange()
+	table.Columns[1].DefaultValue = "a"
+	table.DeleteRow(0)
+	i, err := table.RestoreDeletedRow(0)
+	if err != nil || table.GetValue(i, 1) != nil || table.RowState(i) != UNCHANGE || table.HasChange() {
+		t.Error(err, table.AsCsv())
+	}
+}
@@ -0,0 +1,306 @@
+package datatable
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NoPrimaryKeyError is returned when save the updated or deleted rows of a
+// table without primary key.
+var NoPrimaryKeyError = errors.New("the table has no primary key")
+
+// ConcurrencyError is returned by SaveChanges when the update or delete
+// statements of some rows affected no row, the rows were changed or
+// deleted by others. The transaction begun by SaveChanges is rolled back
+// and all changes of the table are kept, so the application can refresh
+// and retry.
+type ConcurrencyError struct {
+	Rows []*ChangeRow
+}
+
+func (e *ConcurrencyError) Error() string {
+	return fmt.Sprintf("concurrency violation: %d rows affected no row", len(e.Rows))
+}
+
+// SaveOptions are the options of SaveChangesWith.
+type SaveOptions struct {
+	//the WHERE of update and delete includes every origin column value,
+	//not just the primary key
+	CheckAllColumns bool
+	//the WHERE of update and delete includes the origin value of the
+	//version column, the new value is maintained by the application or
+	//the database
+	VersionColumn string
+}
+
+// Executor executes the statements, it is *sql.DB or *sql.Tx.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// txBeginner is the Executor can begin a transaction, such as *sql.DB.
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Dialect generates the database specific parts of the statements.
+type Dialect interface {
+	//Placeholder returns the placeholder of the n-th(from 1) parameter
+	Placeholder(n int) string
+	//QuoteIdent quotes the table or column name
+	QuoteIdent(name string) string
+	//Value converts the decoded value to the parameter value
+	Value(v interface{}) interface{}
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+func (postgresDialect) QuoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+func (postgresDialect) Value(v interface{}) interface{} {
+	return v
+}
+
+// mysqlDialect is the dialect of MySQL, foundRows is true when the
+// connection reports the matched rows of UPDATE.
+type mysqlDialect struct {
+	foundRows bool
+}
+
+func (mysqlDialect) Placeholder(n int) string {
+	return "?"
+}
+func (mysqlDialect) QuoteIdent(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+func (mysqlDialect) Value(v interface{}) interface{} {
+	return boolToInt(v)
+}
+func (d mysqlDialect) reportsChangedRows() bool {
+	return !d.foundRows
+}
+
+// changedRowsDialect is the dialect whose UPDATE reports the changed rows
+// instead of the matched rows, an update affected no row may change no
+// value, so it is not checked.
+type changedRowsDialect interface {
+	reportsChangedRows() bool
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Placeholder(n int) string {
+	return "?"
+}
+func (sqliteDialect) QuoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+func (sqliteDialect) Value(v interface{}) interface{} {
+	return boolToInt(v)
+}
+
+// boolToInt converts the bool to 1 or 0, for the database without boolean
+// type.
+func boolToInt(v interface{}) interface{} {
+	if b, ok := v.(bool); ok {
+		if b {
+			return int64(1)
+		}
+		return int64(0)
+	}
+	return v
+}
+
+var (
+	PostgreSQL Dialect = postgresDialect{}
+	//MySQL reports the changed rows of UPDATE by default, an update of
+	//the same values affects no row, so the updates are not checked for
+	//the concurrency, only the deletes are
+	MySQL Dialect = mysqlDialect{}
+	//MySQLFoundRows is MySQL with the connection reports the matched
+	//rows, the updates are checked too. The DSN of the go-sql-driver
+	//must have clientFoundRows=true
+	MySQLFoundRows Dialect = mysqlDialect{foundRows: true}
+	SQLite         Dialect = sqliteDialect{}
+)
+
+// sqlStatement is a parameterized statement.
+type sqlStatement struct {
+	query string
+	args  []interface{}
+	//the row of the update or delete, it must affect one row
+	row *ChangeRow
+}
+
+// statementBuilder builds the statements of the table changes.
+type statementBuilder struct {
+	table   *DataTable
+	dialect Dialect
+	opts    SaveOptions
+}
+
+// tableName quotes the table name, the schema separated by point is
+// quoted separately.
+func (b *statementBuilder) tableName() string {
+	parts := strings.Split(b.table.TableName, ".")
+	for i, p := range parts {
+		parts[i] = b.dialect.QuoteIdent(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+// where returns the condition of the primary key by the origin values,
+// plus the other columns or the version column by the options.
+func (b *statementBuilder) where(stmt *sqlStatement, origin []interface{}) string {
+	columns := b.table.PK
+	if b.opts.CheckAllColumns {
+		columns = nil
+		for _, c := range b.table.Columns {
+			if !c.IsComputed() {
+				columns = append(columns, c.Name)
+			}
+		}
+	} else if b.opts.VersionColumn != "" && !b.table.IsPrimaryKey(b.opts.VersionColumn) {
+		columns = append(append([]string{}, columns...), b.opts.VersionColumn)
+	}
+	conds := make([]string, len(columns))
+	for i, c := range columns {
+		idx := b.table.ColumnIndex(c)
+		v := b.table.Columns[idx].Decode(origin[idx])
+		if v == nil {
+			conds[i] = b.dialect.QuoteIdent(c) + " IS NULL"
+			continue
+		}
+		stmt.args = append(stmt.args, b.dialect.Value(v))
+		conds[i] = b.dialect.QuoteIdent(c) + " = " + b.dialect.Placeholder(len(stmt.args))
+	}
+	return strings.Join(conds, " AND ")
+}
+func (b *statementBuilder) insert(row *ChangeRow) sqlStatement {
+	stmt := sqlStatement{}
+	var names, params []string
+	for i, c := range b.table.Columns {
+		if c.IsComputed() {
+			continue
+		}
+		stmt.args = append(stmt.args, b.dialect.Value(c.Decode(row.Data[i])))
+		names = append(names, b.dialect.QuoteIdent(c.Name))
+		params = append(params, b.dialect.Placeholder(len(stmt.args)))
+	}
+	stmt.query = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		b.tableName(), strings.Join(names, ", "), strings.Join(params, ", "))
+	return stmt
+}
+func (b *statementBuilder) update(row *ChangeRow) sqlStatement {
+	stmt := sqlStatement{row: row}
+	if d, ok := b.dialect.(changedRowsDialect); ok && d.reportsChangedRows() {
+		stmt.row = nil
+	}
+	var sets []string
+	for i, c := range b.table.Columns {
+		if c.IsComputed() {
+			continue
+		}
+		stmt.args = append(stmt.args, b.dialect.Value(c.Decode(row.Data[i])))
+		sets = append(sets, b.dialect.QuoteIdent(c.Name)+" = "+b.dialect.Placeholder(len(stmt.args)))
+	}
+	stmt.query = fmt.Sprintf("UPDATE %s SET %s WHERE ", b.tableName(), strings.Join(sets, ", "))
+	stmt.query += b.where(&stmt, row.OriginData)
+	return stmt
+}
+func (b *statementBuilder) delete(row *ChangeRow) sqlStatement {
+	stmt := sqlStatement{row: row}
+	stmt.query = fmt.Sprintf("DELETE FROM %s WHERE ", b.tableName())
+	stmt.query += b.where(&stmt, row.OriginData)
+	return stmt
+}
+
+// changeStatements returns the statements of the changes, the deletes
+// first, then the updates and the inserts.
+func (d *DataTable) changeStatements(dialect Dialect, opts SaveOptions) ([]sqlStatement, error) {
+	if opts.VersionColumn != "" && d.ColumnIndex(opts.VersionColumn) == -1 {
+		return nil, ColumnNotFoundError(opts.VersionColumn)
+	}
+	change := d.GetChange()
+	if !d.HasPrimaryKey() && len(change.DeleteRows)+len(change.UpdateRows) > 0 {
+		return nil, NoPrimaryKeyError
+	}
+	b := &statementBuilder{table: d, dialect: dialect, opts: opts}
+	var result []sqlStatement
+	for _, row := range change.DeleteRows {
+		result = append(result, b.delete(row))
+	}
+	for _, row := range change.UpdateRows {
+		result = append(result, b.update(row))
+	}
+	for _, row := range change.InsertRows {
+		result = append(result, b.insert(row))
+	}
+	return result, nil
+}
+
+// SaveChanges applies the changes of GetChange to the database table
+// named TableName by the primary key. When db is a *sql.DB, the statements
+// run in a new transaction and AcceptChange is called after the commit.
+// A *sql.Tx is used as it is, the caller commits it and then calls
+// AcceptChange, so the changes are kept if the caller rolls it back. On
+// error, the changes are kept. An update or delete affected no row returns a *ConcurrencyError.
+// The computed columns are not saved.
+func (d *DataTable) SaveChanges(ctx context.Context, db Executor, dialect Dialect) error {
+	return d.SaveChangesWith(ctx, db, dialect, SaveOptions{})
+}
+
+// SaveChangesWith is SaveChanges with the options of the concurrency
+// check.
+func (d *DataTable) SaveChangesWith(ctx context.Context, db Executor, dialect Dialect, opts SaveOptions) (err error) {
+	stmts, err := d.changeStatements(dialect, opts)
+	if err != nil || len(stmts) == 0 {
+		return err
+	}
+	var tx *sql.Tx
+	exec := db
+	if beginner, ok := db.(txBeginner); ok {
+		if tx, err = beginner.BeginTx(ctx, nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+			}
+		}()
+		exec = tx
+	}
+	var conflicts []*ChangeRow
+	for _, stmt := range stmts {
+		result, err := exec.ExecContext(ctx, stmt.query, stmt.args...)
+		if err != nil {
+			return fmt.Errorf("%s: %v", stmt.query, err)
+		}
+		if stmt.row == nil {
+			continue
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			conflicts = append(conflicts, stmt.row)
+		}
+	}
+	if len(conflicts) > 0 {
+		return &ConcurrencyError{Rows: conflicts}
+	}
+	if tx != nil {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+		d.AcceptChange()
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package datatable
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaveChanges(t *testing.T) {
+	table := NewDataTable("public.orders")
+	table.AddColumn(NewInt64Column("id"))
+	table.AddColumn(StringColumn("name", 0, false))
+	table.AddColumn(NewBoolColumn("valid"))
+	table.AddColumn(TimeColumn("time", false))
+	table.SetPK("id")
+	t1 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	table.AddValues(int64(1), "a", true, t1)
+	table.AddValues(int64(2), "b", false, nil)
+	table.AddValues(int64(3), nil, true, nil)
+	table.AcceptChange()
+	table.SetValues(table.Find(int64(1)), int64(10), "aa", false, t1)
+	table.DeleteRow(table.Find(int64(2)))
+	table.AddValues(int64(4), "d", true, nil)
+
+	state := &fakeDB{}
+	db := openFakeDB(t, state)
+	defer db.Close()
+	if err := table.SaveChanges(context.Background(), db, PostgreSQL); err != nil {
+		t.Fatal(err)
+	}
+	wantExecs := []string{
+		`DELETE FROM "public"."orders" WHERE "id" = $1`,
+		`UPDATE "public"."orders" SET "id" = $1, "name" = $2, "valid" = $3, "time" = $4 WHERE "id" = $5`,
+		`INSERT INTO "public"."orders" ("id", "name", "valid", "time") VALUES ($1, $2, $3, $4)`,
+	}
+	wantArgs := [][]driver.Value{
+		{int64(2)},
+		{int64(10), "aa", false, t1, int64(1)},
+		{int64(4), "d", true, nil},
+	}
+	if !reflect.DeepEqual(state.execs, wantExecs) || !reflect.DeepEqual(state.args, wantArgs) {
+		t.Error(state.execs, state.args)
+	}
+	if table.HasChange() || state.commits != 1 {
+		t.Error("error")
+	}
+	//mysql in the transaction of caller, the changes are kept when the
+	//caller rolls it back
+	table.SetValues(table.Find(int64(10)), int64(6), nil, true, nil)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := table.SaveChanges(context.Background(), tx, MySQL); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	if !table.HasChange() || state.rollbacks != 1 {
+		t.Error("error")
+	}
+	state.execs, state.args = nil, nil
+	if tx, err = db.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.SaveChanges(context.Background(), tx, MySQL); err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+	if !table.HasChange() {
+		t.Error("the caller accepts the changes")
+	}
+	table.AcceptChange()
+	if !reflect.DeepEqual(state.execs, []string{"UPDATE `public`.`orders` SET `id` = ?, `name` = ?, `valid` = ?, `time` = ? WHERE `id` = ?"}) ||
+		!reflect.DeepEqual(state.args, [][]driver.Value{{int64(6), nil, int64(1), nil, int64(10)}}) {
+		t.Error(state.execs, state.args)
+	}
+	//rollback on error,the changes are kept
+	table.DeleteRow(0)
+	table.AddValues(int64(5), "e", true, nil)
+	state.failOn = "INSERT"
+	if err := table.SaveChanges(context.Background(), db, SQLite); err == nil {
+		t.Error("must be error")
+	}
+	if !table.HasChange() || table.GetChange().RowCount != 2 || state.rollbacks != 2 {
+		t.Error("error")
+	}
+	//no primary key
+	table.SetPK()
+	if err := table.SaveChanges(context.Background(), db, SQLite); err != NoPrimaryKeyError {
+		t.Error(err)
+	}
+}
+
+func TestSaveChangesConcurrency(t *testing.T) {
+	table := NewDataTable("orders")
+	table.AddColumn(NewInt64Column("id"))
+	table.AddColumn(StringColumn("name", 0, false))
+	table.AddColumn(NewInt64Column("version"))
+	table.SetPK("id")
+	table.AddValues(int64(1), "a", int64(1))
+	table.AddValues(int64(2), nil, int64(1))
+	table.AddValues(int64(3), "c", int64(1))
+	table.AcceptChange()
+	table.SetValues(0, int64(1), "aa", int64(2))
+	table.DeleteRow(1)
+
+	state := &fakeDB{}
+	db := openFakeDB(t, state)
+	defer db.Close()
+	if err := table.SaveChangesWith(context.Background(), db, PostgreSQL, SaveOptions{CheckAllColumns: true}); err != nil {
+		t.Fatal(err)
+	}
+	wantExecs := []string{
+		`DELETE FROM "orders" WHERE "id" = $1 AND "name" IS NULL AND "version" = $2`,
+		`UPDATE "orders" SET "id" = $1, "name" = $2, "version" = $3 WHERE "id" = $4 AND "name" = $5 AND "version" = $6`,
+	}
+	if !reflect.DeepEqual(state.execs, wantExecs) {
+		t.Error(state.execs)
+	}
+	//the version column
+	table.SetValues(0, int64(1), "aaa", int64(3))
+	table.DeleteRow(1)
+	state.execs, state.args = nil, nil
+	state.notFound = "UPDATE"
+	err := table.SaveChangesWith(context.Background(), db, PostgreSQL, SaveOptions{VersionColumn: "version"})
+	cerr, ok := err.(*ConcurrencyError)
+	if !ok || len(cerr.Rows) != 1 || cerr.Rows[0].Data[1] == nil || *cerr.Rows[0].Data[1].(*string) != "aaa" {
+		t.Fatal(err)
+	}
+	wantExecs = []string{
+		`DELETE FROM "orders" WHERE "id" = $1 AND "version" = $2`,
+		`UPDATE "orders" SET "id" = $1, "name" = $2, "version" = $3 WHERE "id" = $4 AND "version" = $5`,
+	}
+	if !reflect.DeepEqual(state.execs, wantExecs) || !reflect.DeepEqual(state.args[1], []driver.Value{int64(1), "aaa", int64(3), int64(1), int64(2)}) {
+		t.Error(state.execs, state.args)
+	}
+	if table.GetChange().RowCount != 2 || state.rollbacks != 1 || state.commits != 1 {
+		t.Error("the changes must be kept")
+	}
+	if err := table.SaveChangesWith(context.Background(), db, PostgreSQL, SaveOptions{VersionColumn: "nothing"}); err == nil {
+		t.Error("must be error")
+	}
+	//mysql reports no row for the update without changed value
+	table.AcceptChange()
+	table.SetModified(0)
+	state.execs = nil
+	if err := table.SaveChanges(context.Background(), db, MySQL); err != nil || len(state.execs) != 1 {
+		t.Error(err, state.execs)
+	}
+	table.SetModified(0)
+	if err := table.SaveChanges(context.Background(), db, MySQLFoundRows); err == nil {
+		t.Error("must be concurrency error")
+	}
+	//the delete is checked by mysql
+	table.DeleteRow(0)
+	state.notFound = "DELETE"
+	if _, ok := table.SaveChanges(context.Background(), db, MySQL).(*ConcurrencyError); !ok {
+		t.Error("must be concurrency error")
+	}
+}
@@ -0,0 +1,147 @@
+package datatable
+
+import (
+	"errors"
+	"reflect"
+)
+
+// SavepointNotFoundError is returned when roll back to or release a
+// savepoint released or rolled back over.
+var SavepointNotFoundError = errors.New("the savepoint not found")
+
+// Savepoint marks the state of the table, the edits after it can be
+// rolled back by RollbackTo. It belongs to the table created it.
+type Savepoint struct {
+	table *DataTable
+	id    int
+}
+
+// savepoint is the snapshot of the table at a Savepoint.
+type savepoint struct {
+	id          int
+	columns     []*DataColumn
+	pk          []string
+	currentRows *dataRows
+	deleteRows  *dataRows
+	pkIndex     []int
+	rowStatus   []byte
+	changedRows int
+	originData  [][]interface{}
+	indexes     []*dataIndex
+	indexData   [][]int
+	changed     bool
+}
+
+// copy returns a copy of the rows, the stored values are replaced but
+// never modified, so they are shared.
+func (r *dataRows) copy() *dataRows {
+	result := &dataRows{data: make([]interface{}, len(r.data))}
+	for i, v := range r.data {
+		src := reflect.ValueOf(v)
+		dest := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		reflect.Copy(dest, src)
+		result.data[i] = dest.Interface()
+	}
+	return result
+}
+
+// Begin creates a savepoint of the table, the savepoints can be nested.
+// It copies every row, the origin data and the indexes of the table, the
+// cost is like Clone, so begin one for a batch of edits, not for every
+// edit.
+func (d *DataTable) Begin() Savepoint {
+	d.savepointID++
+	sp := &savepoint{id: d.savepointID}
+	sp.capture(d)
+	d.savepoints = append(d.savepoints, sp)
+	return Savepoint{table: d, id: sp.id}
+}
+func (sp *savepoint) capture(d *DataTable) {
+	sp.columns = append([]*DataColumn{}, d.Columns...)
+	sp.pk = append([]string{}, d.PK...)
+	sp.currentRows = d.currentRows.copy()
+	sp.deleteRows = d.deleteRows.copy()
+	sp.pkIndex = append([]int{}, d.primaryIndexes.index...)
+	sp.rowStatus = append([]byte{}, d.rowStatus...)
+	sp.changedRows = d.changedRows
+	sp.originData = append([][]interface{}{}, d.originData...)
+	sp.indexes = append([]*dataIndex{}, d.indexes...)
+	sp.indexData = make([][]int, len(d.indexes))
+	for i, x := range d.indexes {
+		sp.indexData[i] = append([]int{}, x.index...)
+	}
+	sp.changed = d.changed
+}
+func (sp *savepoint) restore(d *DataTable) {
+	//the constraints on the columns added after the savepoint are removed
+	var constraints []tableConstraint
+	for _, c := range d.constraints {
+		used := false
+		for i := len(sp.columns); i < len(d.Columns); i++ {
+			used = used || c.usesColumn(i)
+		}
+		if !used {
+			constraints = append(constraints, c)
+		}
+	}
+	d.constraints = constraints
+	d.Columns = append([]*DataColumn{}, sp.columns...)
+	d.PK = append([]string{}, sp.pk...)
+	d.currentRows = sp.currentRows.copy()
+	d.deleteRows = sp.deleteRows.copy()
+	d.primaryIndexes.index = append([]int{}, sp.pkIndex...)
+	d.rowStatus = append([]byte{}, sp.rowStatus...)
+	d.changedRows = sp.changedRows
+	d.originData = make([][]interface{}, len(sp.originData))
+	for i, v := range sp.originData {
+		if sp.rowStatus[i] == UPDATE {
+			//the columns added after the savepoint are removed
+			d.originData[i] = v[:len(sp.columns):len(sp.columns)]
+		}
+	}
+	d.indexes = append([]*dataIndex{}, sp.indexes...)
+	for i, x := range d.indexes {
+		x.index = append([]int{}, sp.indexData[i]...)
+	}
+	d.changed = sp.changed
+	d.version++
+	d.history.clear()
+}
+
+// savepointPos returns the position of the savepoint in the stack, -1 if
+// not found or the savepoint is of another table.
+func (d *DataTable) savepointPos(sp Savepoint) int {
+	if sp.table != d {
+		return -1
+	}
+	for i, v := range d.savepoints {
+		if v.id == sp.id {
+			return i
+		}
+	}
+	return -1
+}
+
+// RollbackTo rolls the table back to the state at the savepoint, the
+// pending changes before it are kept. The savepoint is kept and the
+// savepoints after it are released.
+func (d *DataTable) RollbackTo(sp Savepoint) error {
+	i := d.savepointPos(sp)
+	if i == -1 {
+		return SavepointNotFoundError
+	}
+	d.savepoints[i].restore(d)
+	d.savepoints = d.savepoints[:i+1]
+	return nil
+}
+
+// Release releases the savepoint and the savepoints after it, the edits
+// are kept.
+func (d *DataTable) Release(sp Savepoint) error {
+	i := d.savepointPos(sp)
+	if i == -1 {
+		return SavepointNotFoundError
+	}
+	d.savepoints = d.savepoints[:i]
+	return nil
+}
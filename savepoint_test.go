@@ -0,0 +1,61 @@
+package datatable
+
+import (
+	"testing"
+)
+
+func TestSavepoint(t *testing.T) {
+	table := CreateTestData()
+	table.CreateIndex("idx", []string{"column3"}, false)
+	table.AcceptChange()
+	table.SetValues(table.Find("bbb", int64(10)), "bbb", int64(10), "before")
+	before := table.AsCsv()
+
+	sp1 := table.Begin()
+	table.SetValues(table.Find("first", int64(10)), "zzz", int64(10), "changed")
+	table.DeleteRow(table.Find("second", int64(1)))
+	sp2 := table.Begin()
+	table.AddValues("new", int64(1), "test1")
+	table.AddColumn(NewStringColumn("column4"))
+	if err := table.RollbackTo(sp2); err != nil {
+		t.Fatal(err)
+	}
+	if table.Find("new", int64(1)) != -1 || table.ColumnCount() != 3 || table.Find("zzz", int64(10)) == -1 {
+		t.Error(table.AsCsv())
+	}
+	if err := table.RollbackTo(sp1); err != nil {
+		t.Fatal(err)
+	}
+	if table.AsCsv() != before || table.GetChange().RowCount != 1 || !table.HasChange() {
+		t.Error(table.AsCsv())
+	}
+	if rows := table.SearchBy("idx", "test1"); len(rows) != 3 {
+		t.Error(rows)
+	}
+	//sp2 is released by rolling back to sp1
+	if err := table.RollbackTo(sp2); err != SavepointNotFoundError {
+		t.Error(err)
+	}
+	//rolled back again
+	table.DeleteAll()
+	if err := table.RollbackTo(sp1); err != nil || table.AsCsv() != before {
+		t.Error(err)
+	}
+	if err := table.Release(sp1); err != nil {
+		t.Error(err)
+	}
+	if err := table.Release(sp1); err != SavepointNotFoundError {
+		t.Error(err)
+	}
+	//the savepoint of another table with the same id
+	table = CreateTestData()
+	other := CreateTestData()
+	osp := other.Begin()
+	table.Begin()
+	if err := table.RollbackTo(osp); err != SavepointNotFoundError {
+		t.Error(err)
+	}
+	if err := other.RollbackTo(osp); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,192 @@
+package datatable
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortColumn is one column of a sort specification.
+type SortColumn struct {
+	Column string
+	Desc   bool
+	//Nulls sort first unless NullsLast is set,in both directions
+	NullsLast bool
+}
+
+// ParseSort parses a sort specification such as
+// "column1 DESC, column2 ASC NULLS LAST".
+func ParseSort(s string) ([]SortColumn, error) {
+	var result []SortColumn
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid sort %q", s)
+		}
+		col := SortColumn{Column: strings.Trim(fields[0], "[]`")}
+		rest := fields[1:]
+		if len(rest) > 0 {
+			switch strings.ToUpper(rest[0]) {
+			case "ASC":
+				rest = rest[1:]
+			case "DESC":
+				col.Desc = true
+				rest = rest[1:]
+			}
+		}
+		if len(rest) == 2 && strings.EqualFold(rest[0], "NULLS") {
+			switch strings.ToUpper(rest[1]) {
+			case "FIRST":
+				rest = nil
+			case "LAST":
+				col.NullsLast = true
+				rest = nil
+			}
+		}
+		if len(rest) > 0 {
+			return nil, fmt.Errorf("invalid sort %q", s)
+		}
+		result = append(result, col)
+	}
+	return result, nil
+}
+
+// sortKeys holds the decoded sort values of some rows.
+type sortKeys struct {
+	columns []SortColumn
+	keys    [][]interface{}
+}
+
+func (d *DataTable) newSortKeys(columns []SortColumn) (*sortKeys, []int, error) {
+	idx := make([]int, len(columns))
+	for i, c := range columns {
+		if idx[i] = d.ColumnIndex(c.Column); idx[i] == -1 {
+			return nil, nil, ColumnNotFoundError(c.Column)
+		}
+	}
+	return &sortKeys{columns: columns}, idx, nil
+}
+
+// compare compares the i and j key,0-equ -1 less 1 large.
+func (s *sortKeys) compare(i, j int) int {
+	for c, col := range s.columns {
+		v1, v2 := s.keys[i][c], s.keys[j][c]
+		if v1 == nil || v2 == nil {
+			if v1 == v2 {
+				continue
+			}
+			if (v1 == nil) != col.NullsLast {
+				return -1
+			}
+			return 1
+		}
+		r := cmpValue(v1, v2)
+		if r == 0 {
+			continue
+		}
+		if col.Desc {
+			return -r
+		}
+		return r
+	}
+	return 0
+}
+
+// order returns the positions of the keys in sorted order,stable.
+func (s *sortKeys) order() []int {
+	order := make([]int, len(s.keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return s.compare(order[i], order[j]) < 0
+	})
+	return order
+}
+
+// sortRowIndexes sorts the row indexes stable by the columns.
+func (d *DataTable) sortRowIndexes(rows []int, columns []SortColumn) error {
+	if len(columns) == 0 {
+		return nil
+	}
+	s, idx, err := d.newSortKeys(columns)
+	if err != nil {
+		return err
+	}
+	s.keys = make([][]interface{}, len(rows))
+	for i, rowIdx := range rows {
+		key := make([]interface{}, len(idx))
+		for j, colIdx := range idx {
+			key[j] = d.GetValue(rowIdx, colIdx)
+		}
+		s.keys[i] = key
+	}
+	order := s.order()
+	sorted := make([]int, len(rows))
+	for i, o := range order {
+		sorted[i] = rows[o]
+	}
+	copy(rows, sorted)
+	return nil
+}
+
+// Select returns the indexes of the rows matching the filter expression,
+// ordered by the sort specification(see ParseSort). An empty filter
+// matches all rows, an empty sort keeps the table order.
+func (d *DataTable) Select(filter string, sort string) ([]int, error) {
+	sortColumns, err := ParseSort(sort)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.selectRows(filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.sortRowIndexes(rows, sortColumns); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+func (d *DataTable) selectRows(filter string) ([]int, error) {
+	rows := []int{}
+	if strings.TrimSpace(filter) == "" {
+		for i := 0; i < d.RowCount(); i++ {
+			rows = append(rows, i)
+		}
+		return rows, nil
+	}
+	expr, err := compileFilter(d, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < d.RowCount(); i++ {
+		ok, err := expr.match(d.GetValues(i))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", i, err)
+		}
+		if ok {
+			rows = append(rows, i)
+		}
+	}
+	return rows, nil
+}
+
+// SelectTable returns a new table with the same columns and primary key,
+// holding the rows matching the filter expression as unchanged rows.
+func (d *DataTable) SelectTable(filter string) (*DataTable, error) {
+	rows, err := d.selectRows(filter)
+	if err != nil {
+		return nil, err
+	}
+	result := d.Clone()
+	for _, i := range rows {
+		if err := result.addValues(d.GetValues(i)); err != nil {
+			return nil, err
+		}
+	}
+	result.AcceptChange()
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package datatable
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func createSelectData() *DataTable {
+	table := NewDataTable("table1")
+	table.AddColumn(NewStringColumn("column1"))
+	table.AddColumn(NewInt64Column("column2"))
+	table.AddColumn(Float64Column("x", false))
+	table.AddColumn(TimeColumn("t", false))
+	table.SetPK("column1")
+	t1 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	table.AddValues("row1", int64(1), 1.5, t1)
+	table.AddValues("row2", int64(6), nil, t1.AddDate(0, 1, 0))
+	table.AddValues("row3", int64(8), 2.5, nil)
+	table.AddValues("other", int64(9), nil, t1.AddDate(0, 2, 0))
+	return table
+}
+func TestSelect(t *testing.T) {
+	table := createSelectData()
+	cases := []struct {
+		filter string
+		sort   string
+		want   []string
+	}{
+		{"", "", []string{"other", "row1", "row2", "row3"}},
+		{"column2 > 5 AND column1 LIKE 'row%' OR x IS NULL", "", []string{"other", "row2", "row3"}},
+		{"column2 > 5 AND (column1 LIKE 'row%' OR x IS NULL)", "column2 desc", []string{"other", "row3", "row2"}},
+		{"x > 1", "x DESC", []string{"row3", "row1"}},
+		{"x <> 1.5", "", []string{"row3"}},
+		{"NOT column1 IN ('row1', 'row2')", "", []string{"other", "row3"}},
+		{"column1 NOT LIKE '*1'", "t NULLS LAST", []string{"row2", "other", "row3"}},
+		{"t >= '2020-02-01T00:00:00Z'", "", []string{"other", "row2"}},
+		{"column2 * 2 + x > 10", "", []string{"row3"}},
+		{"ISNULL(x, 0) = 0 AND LEN(column1) = 4", "", []string{"row2"}},
+		{"IIF(column2 % 2 = 0, 'even', 'odd') = 'even'", "", []string{"row2", "row3"}},
+	}
+	for _, c := range cases {
+		rows, err := table.Select(c.filter, c.sort)
+		if err != nil {
+			t.Error(c.filter, err)
+			continue
+		}
+		got := []string{}
+		for _, i := range rows {
+			got = append(got, table.GetValue(i, 0).(string))
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Error(c.filter, c.sort, got)
+		}
+	}
+}
+func TestSelectError(t *testing.T) {
+	table := createSelectData()
+	for _, filter := range []string{
+		"column2 > 'abc'",
+		"column1 > 5",
+		"column9 = 1",
+		"column2 + 1",
+		"column1 LIKE 5",
+		"(column2 = 1",
+		"column2 = 1 1",
+		"'abc",
+	} {
+		if _, err := table.Select(filter, ""); err == nil {
+			t.Error("must be error:", filter)
+		}
+	}
+	if _, err := table.Select("", "column9"); err == nil {
+		t.Error("must be error")
+	}
+}
+func TestSelectTable(t *testing.T) {
+	table := createSelectData()
+	result, err := table.SelectTable("column2 >= 6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RowCount() != 3 || result.HasChange() || result.Find("row3") == -1 {
+		t.Error("error")
+	}
+}
+
+func TestSelectTableKeepsNulls(t *testing.T) {
+	table := NewDataTable("items")
+	table.AddColumn(NewInt64Column("id"))
+	table.AddColumn(StringColumn("code", 0, false))
+	table.AddColumn(Int64Column("seq", false))
+	table.SetPK("id")
+	table.AddValues(int64(1), nil, nil)
+	table.AddValues(int64(2), "b", int64(5))
+	table.Columns[1].DefaultValue = "a"
+	table.Columns[2].AutoIncrement = true
+	result, err := table.SelectTable("id > 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := result.Find(int64(1))
+	if result.RowCount() != 2 || result.GetValue(i, 1) != nil || result.GetValue(i, 2) != nil {
+		t.Error(result.AsCsv())
+	}
+}
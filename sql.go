@@ -0,0 +1,143 @@
+package datatable
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// sqlColumnType maps the database type of the column to the ColumnType,
+// the scan type is used when the type name is unknown.
+func sqlColumnType(ct *sql.ColumnType) ColumnType {
+	name := strings.ToUpper(ct.DatabaseTypeName())
+	if i := strings.IndexAny(name, "( "); i > -1 {
+		name = name[:i]
+	}
+	switch name {
+	case "INT", "INTEGER", "BIGINT", "SMALLINT", "TINYINT", "MEDIUMINT",
+		"INT2", "INT4", "INT8", "SERIAL", "BIGSERIAL", "SMALLSERIAL":
+		return Int64
+	case "FLOAT", "DOUBLE", "REAL", "FLOAT4", "FLOAT8", "NUMERIC", "DECIMAL", "NUMBER", "MONEY":
+		return Float64
+	case "BOOL", "BOOLEAN", "BIT":
+		return Bool
+	case "DATE", "TIME", "DATETIME", "DATETIME2", "TIMESTAMP", "TIMESTAMPTZ", "TIMETZ":
+		return Time
+	case "BYTEA", "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY", "RAW", "IMAGE":
+		return Bytea
+	case "CHAR", "VARCHAR", "NCHAR", "NVARCHAR", "TEXT", "CLOB", "NCLOB", "VARCHAR2", "NVARCHAR2", "UUID", "JSON":
+		return String
+	}
+	t := ct.ScanType()
+	if t == nil {
+		return String
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Int64
+	case reflect.Float32, reflect.Float64:
+		return Float64
+	case reflect.Bool:
+		return Bool
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Bytea
+		}
+	}
+	switch t {
+	case reflect.TypeOf(time.Time{}), reflect.TypeOf(sql.NullTime{}):
+		return Time
+	case reflect.TypeOf(sql.NullInt64{}), reflect.TypeOf(sql.NullInt32{}):
+		return Int64
+	case reflect.TypeOf(sql.NullFloat64{}):
+		return Float64
+	case reflect.TypeOf(sql.NullBool{}):
+		return Bool
+	}
+	return String
+}
+
+// sqlColumn returns the column of the query result column, the column is
+// nullable unless the driver reports it is not. The MaxSize is not set by
+// the length of the driver, it counts the characters of VARCHAR but
+// MaxSize limits the bytes.
+func sqlColumn(ct *sql.ColumnType) *DataColumn {
+	nullable, ok := ct.Nullable()
+	return NewDataColumn(ct.Name(), sqlColumnType(ct), 0, ok && !nullable)
+}
+
+// FromSQLRows returns a new table holding the rows of the query result,
+// the columns are built from rows.ColumnTypes(), see Fill.
+func FromSQLRows(rows *sql.Rows) (*DataTable, error) {
+	result := NewDataTable("")
+	if err := result.Fill(rows); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Fill adds the rows of the query result as unchanged rows and closes the
+// rows. When the table has no column, the columns are created from
+// rows.ColumnTypes(), otherwise the result columns are matched by name and
+// a column not in the result gets the auto increment or the DefaultValue,
+// else the zero value. On error, the table is rolled back by a savepoint
+// as ReadCsv, no row of the result is kept.
+func (d *DataTable) Fill(rows *sql.Rows) error {
+	defer rows.Close()
+	sp := d.Begin()
+	err := d.fill(rows)
+	if err != nil {
+		d.RollbackTo(sp)
+	}
+	d.Release(sp)
+	return err
+}
+func (d *DataTable) fill(rows *sql.Rows) error {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	if d.ColumnCount() == 0 {
+		for _, ct := range types {
+			if d.ColumnIndex(ct.Name()) > -1 {
+				return fmt.Errorf("column %q: %v", ct.Name(), ColumnExistsError)
+			}
+			d.AddColumn(sqlColumn(ct))
+		}
+	}
+	colIdx := make([]int, len(types))
+	for i, ct := range types {
+		if colIdx[i] = d.ColumnIndex(ct.Name()); colIdx[i] == -1 {
+			return ColumnNotFoundError(ct.Name())
+		}
+	}
+	changed := d.changed
+	defer func() {
+		d.changed = changed
+	}()
+	dest := make([]interface{}, len(types))
+	for rows.Next() {
+		//scan into the store type,the nullable column is a pointer
+		for i, c := range colIdx {
+			dest[i] = reflect.New(d.Columns[c].StoreType()).Interface()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		values := d.missingValues()
+		for i, c := range colIdx {
+			values[c] = d.Columns[c].Decode(reflect.ValueOf(dest[i]).Elem().Interface())
+		}
+		if err := d.AddValues(values...); err != nil {
+			return err
+		}
+		d.setRowStatus(len(d.rowStatus)-1, UNCHANGE)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,300 @@
+package datatable
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeColumn is a column of the fake query result.
+type fakeColumn struct {
+	name     string
+	typeName string
+	nullable bool
+	length   int64
+}
+
+// fakeDB is the state of a fake database, the query returns the columns
+// and rows, the executed statements are recorded.
+type fakeDB struct {
+	sync.Mutex
+	columns []fakeColumn
+	rows    [][]driver.Value
+	execs   []string
+	args    [][]driver.Value
+	//the exec of the statement contains failOn returns error
+	failOn string
+	//the exec of the statement contains notFound affects no row
+	notFound  string
+	commits   int
+	rollbacks int
+}
+
+var (
+	fakeDBs    = map[string]*fakeDB{}
+	fakeDBsMux sync.Mutex
+)
+
+func init() {
+	sql.Register("fakedb", fakeDriver{})
+}
+
+// openFakeDB returns a database of the fake driver with the state.
+func openFakeDB(t *testing.T, state *fakeDB) *sql.DB {
+	fakeDBsMux.Lock()
+	fakeDBs[t.Name()] = state
+	fakeDBsMux.Unlock()
+	db, err := sql.Open("fakedb", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMux.Lock()
+	defer fakeDBsMux.Unlock()
+	return &fakeConn{db: fakeDBs[name]}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+func (c *fakeConn) Close() error {
+	return nil
+}
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{db: c.db}, nil
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (t fakeTx) Commit() error {
+	t.db.Lock()
+	defer t.db.Unlock()
+	t.db.commits++
+	return nil
+}
+func (t fakeTx) Rollback() error {
+	t.db.Lock()
+	defer t.db.Unlock()
+	t.db.rollbacks++
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.Lock()
+	defer s.db.Unlock()
+	if s.db.failOn != "" && strings.Contains(s.query, s.db.failOn) {
+		return nil, errors.New("exec failed")
+	}
+	s.db.execs = append(s.db.execs, s.query)
+	s.db.args = append(s.db.args, args)
+	if s.db.notFound != "" && strings.Contains(s.query, s.db.notFound) {
+		return driver.RowsAffected(0), nil
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{db: s.db}, nil
+}
+
+type fakeRows struct {
+	db  *fakeDB
+	pos int
+}
+
+func (r *fakeRows) Columns() []string {
+	result := make([]string, len(r.db.columns))
+	for i, c := range r.db.columns {
+		result[i] = c.name
+	}
+	return result
+}
+func (r *fakeRows) Close() error {
+	return nil
+}
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.db.rows) {
+		return io.EOF
+	}
+	copy(dest, r.db.rows[r.pos])
+	r.pos++
+	return nil
+}
+func (r *fakeRows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.db.columns[index].typeName
+}
+func (r *fakeRows) ColumnTypeNullable(index int) (bool, bool) {
+	return r.db.columns[index].nullable, true
+}
+func (r *fakeRows) ColumnTypeLength(index int) (int64, bool) {
+	return r.db.columns[index].length, r.db.columns[index].length > 0
+}
+
+func TestFill(t *testing.T) {
+	t1 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	db := openFakeDB(t, &fakeDB{
+		columns: []fakeColumn{
+			{"id", "BIGINT", false, 0},
+			{"name", "VARCHAR", true, 20},
+			{"price", "NUMERIC(10,2)", true, 0},
+			{"valid", "BOOL", false, 0},
+			{"created", "TIMESTAMP", true, 0},
+			{"data", "BYTEA", true, 0},
+		},
+		rows: [][]driver.Value{
+			{int64(1), "a", 1.5, true, t1, []byte{1}},
+			{int64(2), nil, nil, false, nil, nil},
+		},
+	})
+	defer db.Close()
+	rows, err := db.Query("select * from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := FromSQLRows(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*DataColumn{
+		NewDataColumn("id", Int64, 0, true),
+		NewDataColumn("name", String, 0, false),
+		NewDataColumn("price", Float64, 0, false),
+		NewDataColumn("valid", Bool, 0, true),
+		NewDataColumn("created", Time, 0, false),
+		NewDataColumn("data", Bytea, 0, false),
+	}
+	for i, c := range want {
+		if c.Name != table.Columns[i].Name || c.DataType != table.Columns[i].DataType ||
+			c.NotNull != table.Columns[i].NotNull || c.MaxSize != table.Columns[i].MaxSize {
+			t.Error(table.Columns[i])
+		}
+	}
+	if !reflect.DeepEqual(table.GetValues(0), []interface{}{int64(1), "a", 1.5, true, t1, []byte{1}}) ||
+		!reflect.DeepEqual(table.GetValues(1), []interface{}{int64(2), nil, nil, false, nil, nil}) {
+		t.Error(table.Rows())
+	}
+	if table.HasChange() || table.GetChange().RowCount != 0 {
+		t.Error("error")
+	}
+	//fill the existing table with primary key
+	table = NewDataTable("t")
+	table.AddColumn(NewInt64Column("id"))
+	table.AddColumn(StringColumn("name", 0, false))
+	table.AddColumn(NewStringColumn("other"))
+	table.SetPK("id")
+	table.AddValues(int64(2), "b", "x")
+	if rows, err = db.Query("select id,name from t"); err != nil {
+		t.Fatal(err)
+	}
+	if err = table.Fill(rows); err == nil {
+		t.Error("must be error")
+	}
+	db.Close()
+	db = openFakeDB(t, &fakeDB{
+		columns: []fakeColumn{{"name", "TEXT", true, 0}, {"id", "INTEGER", false, 0}},
+		rows:    [][]driver.Value{{"c", int64(3)}, {nil, int64(1)}},
+	})
+	if rows, err = db.Query("select name,id from t"); err != nil {
+		t.Fatal(err)
+	}
+	if err = table.Fill(rows); err != nil {
+		t.Fatal(err)
+	}
+	if table.RowCount() != 3 || len(table.GetChange().InsertRows) != 1 || table.Row(0)["name"] != nil || table.Row(2)["other"] != "" {
+		t.Error(table.Rows())
+	}
+	//the multi-byte string fits the column
+	db.Close()
+	db = openFakeDB(t, &fakeDB{
+		columns: []fakeColumn{{"name", "VARCHAR", true, 2}},
+		rows:    [][]driver.Value{{"中文"}},
+	})
+	if rows, err = db.Query("select name from t"); err != nil {
+		t.Fatal(err)
+	}
+	if table, err = FromSQLRows(rows); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.SetValues(0, "文字"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFillRollback(t *testing.T) {
+	db := openFakeDB(t, &fakeDB{
+		columns: []fakeColumn{{"id", "BIGINT", false, 0}},
+		rows:    [][]driver.Value{{int64(1)}, {int64(2)}, {int64(1)}},
+	})
+	defer db.Close()
+	table := NewDataTable("t")
+	table.AddColumn(NewInt64Column("id"))
+	table.SetPK("id")
+	table.AddValues(int64(5))
+	table.AcceptChange()
+	rows, err := db.Query("select id from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Fill(rows); err != KeyValueExists {
+		t.Error(err)
+	}
+	if table.RowCount() != 1 || table.HasChange() {
+		t.Error(table.Rows())
+	}
+}
+func TestFillMissingColumns(t *testing.T) {
+	db := openFakeDB(t, &fakeDB{
+		columns: []fakeColumn{{"name", "VARCHAR", false, 20}},
+		rows:    [][]driver.Value{{"a"}, {"b"}},
+	})
+	defer db.Close()
+	table := NewDataTable("items")
+	id := NewInt64Column("id")
+	id.AutoIncrement = true
+	id.AutoIncrementSeed = 1
+	table.AddColumn(id)
+	table.AddColumn(NewStringColumn("name"))
+	status := NewStringColumn("status")
+	status.DefaultValue = "new"
+	table.AddColumn(status)
+	table.AddColumn(NewInt64Column("qty"))
+	table.SetPK("id")
+	rows, err := db.Query("select name from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the missing columns are filled by the auto increment and the default
+	if err := table.Fill(rows); err != nil {
+		t.Fatal(err)
+	}
+	if table.AsCsv() != "id,name,status,qty\n1,a,new,0\n2,b,new,0\n" || table.HasChange() {
+		t.Error(table.AsCsv())
+	}
+}